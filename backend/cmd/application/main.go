@@ -10,11 +10,15 @@ import (
 	"github.com/go-playground/validator/v10"
 	"github.com/jonathanhu237/when-works/backend/internal/application"
 	"github.com/jonathanhu237/when-works/backend/internal/config"
+	"github.com/jonathanhu237/when-works/backend/internal/lockout"
 	"github.com/jonathanhu237/when-works/backend/internal/logger"
 	"github.com/jonathanhu237/when-works/backend/internal/mailer"
 	"github.com/jonathanhu237/when-works/backend/internal/models"
+	"github.com/jonathanhu237/when-works/backend/internal/ratelimit"
+	"github.com/redis/go-redis/v9"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "time/tzdata"
 )
 
 func main() {
@@ -82,10 +86,54 @@ func main() {
 	}
 	logger.Info("mailer initialized successfully")
 
+	// ------------------------------
+	// Connect to Redis if anything is kept there
+	// ------------------------------
+	var redisClient *redis.Client
+	if cfg.Lockout.Backend == config.RedisBackend || cfg.RateLimit.Backend == config.RedisBackend {
+		redisClient = redis.NewClient(&redis.Options{
+			Addr:     fmt.Sprintf("%s:%s", cfg.Redis.Host, cfg.Redis.Port),
+			Password: cfg.Redis.Password,
+			DB:       cfg.Redis.DB,
+		})
+		defer redisClient.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Database.PingTimeout)*time.Second)
+		defer cancel()
+
+		if err := redisClient.Ping(ctx).Err(); err != nil {
+			logger.Error("error pinging redis", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("redis connection established")
+	}
+
+	// ------------------------------
+	// Initialize login lockout store
+	// ------------------------------
+	var lockoutStore lockout.Store
+	switch cfg.Lockout.Backend {
+	case config.RedisBackend:
+		lockoutStore = lockout.NewRedisStore(redisClient)
+	default:
+		lockoutStore = lockout.NewMemoryStore()
+	}
+
+	// ------------------------------
+	// Initialize rate limit store
+	// ------------------------------
+	var rateLimitStore ratelimit.Store
+	switch cfg.RateLimit.Backend {
+	case config.RedisBackend:
+		rateLimitStore = ratelimit.NewRedisStore(redisClient)
+	default:
+		rateLimitStore = ratelimit.NewMemoryStore()
+	}
+
 	// ------------------------------
 	// Initialize application
 	// ------------------------------
-	app := application.New(cfg, logger, models, validator, mailer)
+	app := application.New(cfg, logger, models, validator, mailer, lockoutStore, rateLimitStore)
 	if err := app.Init(); err != nil {
 		logger.Error("error during application initialization", "error", err)
 		os.Exit(1)
@@ -0,0 +1,96 @@
+package application
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/jonathanhu237/when-works/backend/internal/models"
+)
+
+func (app *Application) ListMyAPITokensHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	tokens, err := app.models.APIToken.GetForUser(requester.UserID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"tokens": tokens}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// CreateMyAPITokenHandler creates a personal access token. The plaintext is
+// in the response only; it cannot be shown again.
+func (app *Application) CreateMyAPITokenHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	var input struct {
+		Name          string   `json:"name" validate:"required,max=100"`
+		Scopes        []string `json:"scopes" validate:"required,min=1,unique,dive,oneof=read write schedule-admin admin"`
+		ExpiresInDays int      `json:"expires_in_days" validate:"required,min=1,max=365"`
+	}
+
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.validator.Struct(input); err != nil {
+		app.failedValidationResponse(w, r, err)
+		return
+	}
+
+	// Admin scopes only make sense for users with some permission or a team
+	// to manage, and the admin routes would refuse the rest anyway
+	adminScopes := []string{models.APIScopeScheduleAdmin, models.APIScopeAdmin}
+	if len(requester.Permissions) == 0 && len(requester.ManagedTeamIDs) == 0 && slices.ContainsFunc(input.Scopes, func(s string) bool { return slices.Contains(adminScopes, s) }) {
+		app.errorResponse(w, r, http.StatusForbidden, "API_TOKEN_SCOPE_FORBIDDEN", "only admins can create tokens with admin scopes", nil)
+		return
+	}
+
+	token := &models.APIToken{
+		UserID:    requester.UserID,
+		Name:      input.Name,
+		Scopes:    input.Scopes,
+		ExpiresAt: time.Now().AddDate(0, 0, input.ExpiresInDays),
+	}
+
+	plaintext, err := app.models.APIToken.Insert(token)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := map[string]any{"token": token, "plaintext": plaintext}
+	if err := app.writeJSON(w, http.StatusCreated, response, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) RevokeMyAPITokenHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	tokenID, err := app.readUUIDParam(r, "tokenID", "token id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.models.APIToken.Revoke(tokenID, requester.UserID); err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.errorResponse(w, r, http.StatusNotFound, "API_TOKEN_NOT_FOUND", "access token not found", nil)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusNoContent, nil, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
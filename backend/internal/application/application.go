@@ -3,11 +3,14 @@ package application
 import (
 	"log/slog"
 	"sync"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/jonathanhu237/when-works/backend/internal/config"
+	"github.com/jonathanhu237/when-works/backend/internal/lockout"
 	"github.com/jonathanhu237/when-works/backend/internal/mailer"
 	"github.com/jonathanhu237/when-works/backend/internal/models"
+	"github.com/jonathanhu237/when-works/backend/internal/ratelimit"
 )
 
 type Application struct {
@@ -17,6 +20,12 @@ type Application struct {
 	validator *validator.Validate
 	mailer    *mailer.Mailer
 	wg        sync.WaitGroup
+
+	// Failed logins are tracked per username and per client IP
+	usernameLockout *lockout.Limiter
+	ipLockout       *lockout.Limiter
+
+	rateLimitStore ratelimit.Store
 }
 
 func New(
@@ -25,12 +34,26 @@ func New(
 	models models.Models,
 	validator *validator.Validate,
 	mailer *mailer.Mailer,
+	lockoutStore lockout.Store,
+	rateLimitStore ratelimit.Store,
 ) *Application {
+	policy := lockout.Policy{
+		BaseDelay: time.Duration(cfg.Lockout.BaseDelay) * time.Second,
+		MaxDelay:  time.Duration(cfg.Lockout.MaxDelay) * time.Second,
+		Window:    time.Duration(cfg.Lockout.Window) * time.Second,
+	}
+	usernamePolicy, ipPolicy := policy, policy
+	usernamePolicy.Threshold = cfg.Lockout.UsernameThreshold
+	ipPolicy.Threshold = cfg.Lockout.IPThreshold
+
 	return &Application{
-		config:    cfg,
-		logger:    logger,
-		models:    models,
-		validator: validator,
-		mailer:    mailer,
+		config:          cfg,
+		logger:          logger,
+		models:          models,
+		validator:       validator,
+		mailer:          mailer,
+		usernameLockout: lockout.NewLimiter(lockoutStore, "username", usernamePolicy),
+		ipLockout:       lockout.NewLimiter(lockoutStore, "ip", ipPolicy),
+		rateLimitStore:  rateLimitStore,
 	}
 }
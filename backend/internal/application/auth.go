@@ -3,18 +3,21 @@ package application
 import (
 	"errors"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"github.com/jonathanhu237/when-works/backend/internal/config"
 	"github.com/jonathanhu237/when-works/backend/internal/models"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type CustomClaims struct {
-	UserID   string `json:"user_id"`
-	Username string `json:"username"`
-	IsAdmin  bool   `json:"is_admin"`
+	UserID    string `json:"user_id"`
+	Username  string `json:"username"`
+	OrgID     string `json:"org_id"`
+	SessionID string `json:"sid"`
 	jwt.RegisteredClaims
 }
 
@@ -33,79 +36,257 @@ func (app *Application) LoginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Refuse outright while the username or the client is locked out
+	wait, err := app.loginLockedFor(r.Context(), input.Username, app.clientIP(r))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if wait > 0 {
+		app.loginLockedResponse(w, r, wait)
+		return
+	}
+
 	// Get user by username
 	user, err := app.models.User.GetByUsername(input.Username)
 	if err != nil {
 		switch {
 		case errors.Is(err, models.ErrRecordNotFound):
-			app.invalidCredentialsResponse(w, r)
+			app.invalidLoginResponse(w, r, input.Username)
 		default:
 			app.internalServerError(w, r, err)
 		}
 		return
 	}
 
+	// Invited users have no password until they activate the account, so any
+	// attempt fails like a wrong password and does not reveal the invitation
+	if user.Status == models.UserStatusInvited {
+		app.invalidLoginResponse(w, r, input.Username)
+		return
+	}
+
 	// Check password
 	if err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(input.Password)); err != nil {
 		switch {
 		case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
-			app.invalidCredentialsResponse(w, r)
+			app.invalidLoginResponse(w, r, input.Username)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	// Deactivated users are only told so once they proved who they are
+	if user.Status == models.UserStatusDeactivated {
+		app.errorResponse(w, r, http.StatusForbidden, "USER_DEACTIVATED", "the account has been deactivated", nil)
+		return
+	}
+
+	// With two-factor authentication on, the password only earns a challenge
+	// that LoginTOTPHandler completes
+	totp, err := app.models.TOTP.Get(user.ID)
+	if err != nil && !errors.Is(err, models.ErrRecordNotFound) {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if totp != nil && totp.Enabled() {
+		challenge, err := app.models.Token.New(user.ID, totpChallengeTTL, models.ScopeTOTPChallenge)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		response := map[string]any{"totp_required": true, "challenge": challenge}
+		if err := app.writeJSON(w, http.StatusOK, response, nil); err != nil {
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.startSession(w, r, user); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	// Return
+	if err = app.writeJSON(w, http.StatusOK, map[string]any{"user": user}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// startSession starts a session for the user and sets its tokens as cookies.
+// The login is complete at this point, so earlier failures of the username
+// are forgotten.
+func (app *Application) startSession(w http.ResponseWriter, r *http.Request, user *models.User) error {
+	if err := app.usernameLockout.Reset(r.Context(), strings.ToLower(user.Username)); err != nil {
+		return err
+	}
+
+	session, refreshToken, err := app.models.Session.Create(user.ID, r.UserAgent(), app.clientIP(r))
+	if err != nil {
+		return err
+	}
+
+	return app.setAuthCookies(w, user, session.ID, refreshToken)
+}
+
+// RefreshHandler trades the refresh token cookie for a new access token and
+// the next refresh token of the same session.
+func (app *Application) RefreshHandler(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie("refreshToken")
+	if err != nil {
+		app.unauthorizedResponse(w, r)
+		return
+	}
+
+	session, refreshToken, err := app.models.Session.Rotate(cookie.Value)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRefreshTokenReused):
+			app.logger.Warn("refresh token reused, session revoked")
+			app.clearAuthCookies(w)
+			app.unauthorizedResponse(w, r)
+		case errors.Is(err, models.ErrInvalidRefreshToken):
+			app.clearAuthCookies(w)
+			app.unauthorizedResponse(w, r)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	user, err := app.models.User.GetByID(session.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.clearAuthCookies(w)
+			app.unauthorizedResponse(w, r)
 		default:
 			app.internalServerError(w, r, err)
 		}
 		return
 	}
 
-	// Generate JWT token
-	expirationTime := time.Now().Add(time.Duration(app.config.JWT.Expiration) * time.Second)
+	// Deactivated or archived since the session started
+	if user.Status != models.UserStatusActive {
+		if err := app.models.Session.Revoke(session.ID); err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+		app.clearAuthCookies(w)
+		app.unauthorizedResponse(w, r)
+		return
+	}
+
+	if err := app.setAuthCookies(w, user, session.ID, refreshToken); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"user": user}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// LogoutHandler revokes the session so neither its access token nor its
+// refresh token can be used again, then clears the cookies.
+func (app *Application) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie("refreshToken"); err == nil {
+		if err := app.models.Session.RevokeByRefreshToken(cookie.Value); err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	if cookie, err := r.Cookie("accessToken"); err == nil {
+		if claims, err := app.parseAccessToken(cookie.Value); err == nil {
+			if sessionID, err := uuid.Parse(claims.SessionID); err == nil {
+				if err := app.models.Session.Revoke(sessionID); err != nil {
+					app.internalServerError(w, r, err)
+					return
+				}
+			}
+		}
+	}
+
+	app.clearAuthCookies(w)
+
+	// Return success response
+	if err := app.writeJSON(w, http.StatusNoContent, nil, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// setAuthCookies signs a short-lived access token for the session and sets it
+// together with the refresh token. The refresh token cookie is always
+// HttpOnly: only the refresh endpoint needs it.
+func (app *Application) setAuthCookies(w http.ResponseWriter, user *models.User, sessionID uuid.UUID, refreshToken string) error {
+	now := time.Now()
+	expirationTime := now.Add(time.Duration(app.config.JWT.Expiration) * time.Second)
 	claims := CustomClaims{
-		UserID:   user.ID.String(),
-		Username: user.Username,
-		IsAdmin:  user.IsAdmin,
+		UserID:    user.ID.String(),
+		Username:  user.Username,
+		OrgID:     user.OrgID.String(),
+		SessionID: sessionID.String(),
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	accessToken, err := token.SignedString([]byte(app.config.JWT.Secret))
 	if err != nil {
-		app.internalServerError(w, r, err)
-		return
+		return err
 	}
 
-	// Set JWT as HttpOnly cookie
-	cookie := &http.Cookie{
+	http.SetCookie(w, &http.Cookie{
 		Name:     "accessToken",
 		Value:    accessToken,
 		Path:     "/",
 		Expires:  expirationTime,
 		HttpOnly: app.config.Environment == config.Production,
 		SameSite: http.SameSiteStrictMode,
-	}
-	http.SetCookie(w, cookie)
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     "refreshToken",
+		Value:    refreshToken,
+		Path:     "/",
+		Expires:  now.Add(time.Duration(app.config.JWT.RefreshExpiration) * time.Second),
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
 
-	// Return
-	if err = app.writeJSON(w, http.StatusOK, map[string]any{"user": user}, nil); err != nil {
-		app.internalServerError(w, r, err)
+	return nil
+}
+
+func (app *Application) clearAuthCookies(w http.ResponseWriter) {
+	for _, name := range []string{"accessToken", "refreshToken"} {
+		http.SetCookie(w, &http.Cookie{
+			Name:     name,
+			Value:    "",
+			Path:     "/",
+			Expires:  time.Now().Add(-time.Hour),
+			HttpOnly: name == "refreshToken" || app.config.Environment == config.Production,
+			SameSite: http.SameSiteStrictMode,
+		})
 	}
 }
 
-func (app *Application) LogoutHandler(w http.ResponseWriter, r *http.Request) {
-	// Clear the accessToken cookie
-	cookie := &http.Cookie{
-		Name:     "accessToken",
-		Value:    "",
-		Path:     "/",
-		Expires:  time.Now().Add(-time.Hour),
-		HttpOnly: app.config.Environment == config.Production,
-		SameSite: http.SameSiteStrictMode,
+// parseAccessToken verifies the signature and expiry of an access token.
+func (app *Application) parseAccessToken(tokenString string) (*CustomClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &CustomClaims{}, func(token *jwt.Token) (any, error) {
+		return []byte(app.config.JWT.Secret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return nil, err
 	}
-	http.SetCookie(w, cookie)
 
-	// Return success response
-	if err := app.writeJSON(w, http.StatusNoContent, nil, nil); err != nil {
-		app.internalServerError(w, r, err)
+	claims, ok := token.Claims.(*CustomClaims)
+	if !ok {
+		return nil, errors.New("unexpected token claims")
 	}
+
+	return claims, nil
 }
@@ -0,0 +1,129 @@
+package application
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jonathanhu237/when-works/backend/internal/models"
+)
+
+type availabilityWindowInput struct {
+	DayOfWeek *int   `json:"day_of_week" validate:"required,min=0,max=6"`
+	StartTime string `json:"start_time" validate:"required,clock"`
+	EndTime   string `json:"end_time" validate:"required,clock"`
+}
+
+type availabilityInput struct {
+	TimeZone string                    `json:"time_zone" validate:"required,timezone"`
+	Windows  []availabilityWindowInput `json:"windows" validate:"max=100,dive"`
+}
+
+func (app *Application) GetMyAvailabilityHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	availability, err := app.models.Availability.GetByUserID(requester.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.errorResponse(w, r, http.StatusNotFound, "USER_NOT_FOUND", "user not found", nil)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"availability": availability}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) ReplaceMyAvailabilityHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	var input availabilityInput
+
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.validator.Struct(input); err != nil {
+		app.failedValidationResponse(w, r, err)
+		return
+	}
+
+	availability := &models.Availability{
+		TimeZone: input.TimeZone,
+		Windows:  make([]models.AvailabilityWindow, 0, len(input.Windows)),
+	}
+	for _, window := range input.Windows {
+		availability.Windows = append(availability.Windows, models.AvailabilityWindow{
+			DayOfWeek: *window.DayOfWeek,
+			StartTime: window.StartTime,
+			EndTime:   window.EndTime,
+		})
+	}
+
+	if err := app.models.Availability.Replace(requester.UserID, availability); err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.errorResponse(w, r, http.StatusNotFound, "USER_NOT_FOUND", "user not found", nil)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"availability": availability}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// maxResolveSpan caps how far a single request may expand recurring windows.
+const maxResolveSpan = 93 * 24 * time.Hour
+
+func (app *Application) GetMyResolvedAvailabilityHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+	app.writeResolvedAvailability(w, r, requester.UserID)
+}
+
+func (app *Application) GetUserAvailabilityHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := app.readUUIDParam(r, "userID", "user id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	app.writeResolvedAvailability(w, r, userID)
+}
+
+func (app *Application) writeResolvedAvailability(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	from, to, err := app.readTimeRange(r.URL.Query(), maxResolveSpan)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	intervals, err := app.models.Availability.Resolve(userID, from, to)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.errorResponse(w, r, http.StatusNotFound, "USER_NOT_FOUND", "user not found", nil)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	response := map[string]any{
+		"user_id":   userID,
+		"from":      from,
+		"to":        to,
+		"intervals": intervals,
+	}
+	if err := app.writeJSON(w, http.StatusOK, response, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
@@ -0,0 +1,200 @@
+package application
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jonathanhu237/when-works/backend/internal/models"
+)
+
+type availabilityExceptionInput struct {
+	Kind    string    `json:"kind" validate:"required,oneof=unavailable available"`
+	StartAt time.Time `json:"start_at" validate:"required"`
+	EndAt   time.Time `json:"end_at" validate:"required,gtfield=StartAt"`
+	Note    string    `json:"note" validate:"max=500"`
+}
+
+func (app *Application) CreateMyAvailabilityExceptionHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	var input availabilityExceptionInput
+
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.validator.Struct(input); err != nil {
+		app.failedValidationResponse(w, r, err)
+		return
+	}
+
+	exception := &models.AvailabilityException{
+		UserID:  requester.UserID,
+		Kind:    models.AvailabilityExceptionKind(input.Kind),
+		StartAt: input.StartAt,
+		EndAt:   input.EndAt,
+		Note:    input.Note,
+	}
+
+	if err := app.models.Availability.InsertException(exception); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusCreated, map[string]any{"exception": exception}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) ListMyAvailabilityExceptionsHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	from, to, err := app.readTimeRange(r.URL.Query(), maxResolveSpan)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	exceptions, err := app.models.Availability.GetExceptions(requester.UserID, from, to)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"exceptions": exceptions}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) GetMyAvailabilityExceptionHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	exceptionID, err := app.readUUIDParam(r, "exceptionID", "exception id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	exception, err := app.models.Availability.GetException(exceptionID, requester.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.errorResponse(w, r, http.StatusNotFound, "EXCEPTION_NOT_FOUND", "availability exception not found", nil)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"exception": exception}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) UpdateMyAvailabilityExceptionHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	exceptionID, err := app.readUUIDParam(r, "exceptionID", "exception id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var input struct {
+		Kind    *string    `json:"kind"`
+		StartAt *time.Time `json:"start_at"`
+		EndAt   *time.Time `json:"end_at"`
+		Note    *string    `json:"note"`
+	}
+
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Kind == nil && input.StartAt == nil && input.EndAt == nil && input.Note == nil {
+		app.badRequestResponse(w, r, errors.New("at least one field must be provided"))
+		return
+	}
+
+	exception, err := app.models.Availability.GetException(exceptionID, requester.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.errorResponse(w, r, http.StatusNotFound, "EXCEPTION_NOT_FOUND", "availability exception not found", nil)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	// Validate the merged result so that partial updates cannot invert the range
+	merged := availabilityExceptionInput{
+		Kind:    string(exception.Kind),
+		StartAt: exception.StartAt,
+		EndAt:   exception.EndAt,
+		Note:    exception.Note,
+	}
+	if input.Kind != nil {
+		merged.Kind = *input.Kind
+	}
+	if input.StartAt != nil {
+		merged.StartAt = *input.StartAt
+	}
+	if input.EndAt != nil {
+		merged.EndAt = *input.EndAt
+	}
+	if input.Note != nil {
+		merged.Note = *input.Note
+	}
+
+	if err := app.validator.Struct(merged); err != nil {
+		app.failedValidationResponse(w, r, err)
+		return
+	}
+
+	exception.Kind = models.AvailabilityExceptionKind(merged.Kind)
+	exception.StartAt = merged.StartAt
+	exception.EndAt = merged.EndAt
+	exception.Note = merged.Note
+
+	if err := app.models.Availability.UpdateException(exception); err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.errorResponse(w, r, http.StatusNotFound, "EXCEPTION_NOT_FOUND", "availability exception not found", nil)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"exception": exception}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) DeleteMyAvailabilityExceptionHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	exceptionID, err := app.readUUIDParam(r, "exceptionID", "exception id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.models.Availability.DeleteException(exceptionID, requester.UserID); err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.errorResponse(w, r, http.StatusNotFound, "EXCEPTION_NOT_FOUND", "availability exception not found", nil)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusNoContent, nil, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
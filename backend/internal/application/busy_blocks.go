@@ -0,0 +1,155 @@
+package application
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/jonathanhu237/when-works/backend/internal/ical"
+	"github.com/jonathanhu237/when-works/backend/internal/models"
+)
+
+// Recurring events are expanded over a fixed horizon around the import, which
+// covers any range the availability endpoints will be asked about for a year.
+const (
+	busyImportPast      = 31 * 24 * time.Hour
+	busyImportAhead     = 366 * 24 * time.Hour
+	busyImportMaxBlocks = 5000
+	busyImportMaxBytes  = 2 << 20
+)
+
+// ImportMyBusyBlocksHandler replaces the user's busy blocks with the events of
+// an uploaded .ics file. The file is sent either as the request body with a
+// text/calendar content type or as the "file" field of a multipart form.
+func (app *Application) ImportMyBusyBlocksHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	r.Body = http.MaxBytesReader(w, r.Body, busyImportMaxBytes)
+
+	var file io.Reader = r.Body
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/calendar":
+	case "multipart/form-data":
+		part, _, err := r.FormFile("file")
+		if err != nil {
+			app.badRequestResponse(w, r, errors.New("multipart form must contain a \"file\" field"))
+			return
+		}
+		defer part.Close()
+		file = part
+	default:
+		app.badRequestResponse(w, r, errors.New("content type must be text/calendar or multipart/form-data"))
+		return
+	}
+
+	user, err := app.models.User.GetByID(requester.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.errorResponse(w, r, http.StatusNotFound, "USER_NOT_FOUND", "user not found", nil)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	location, err := time.LoadLocation(user.TimeZone)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	calendar, err := ical.Parse(file, location)
+	if err != nil {
+		var parseError *ical.ParseError
+		var maxBytesError *http.MaxBytesError
+
+		switch {
+		case errors.As(err, &parseError):
+			app.badRequestResponse(w, r, err)
+		case errors.As(err, &maxBytesError):
+			app.badRequestResponse(w, r, fmt.Errorf("file must not be larger than %d bytes", maxBytesError.Limit))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	now := time.Now().UTC()
+	from, to := now.Add(-busyImportPast), now.Add(busyImportAhead)
+
+	occurrences, err := ical.Expand(calendar.Events, from, to, busyImportMaxBlocks)
+	if err != nil {
+		switch {
+		case errors.Is(err, ical.ErrTooManyOccurrences):
+			app.badRequestResponse(w, r, fmt.Errorf("calendar has more than %d events in the import range", busyImportMaxBlocks))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	blocks := []models.BusyBlock{}
+	for _, occurrence := range occurrences {
+		// Free and cancelled events do not block time
+		if occurrence.Event.Transparent || occurrence.Event.Cancelled || !occurrence.End.After(occurrence.Start) {
+			continue
+		}
+		blocks = append(blocks, models.BusyBlock{
+			StartAt: occurrence.Start.UTC(),
+			EndAt:   occurrence.End.UTC(),
+			Summary: occurrence.Event.Summary,
+		})
+	}
+
+	if err := app.models.Availability.ReplaceBusyBlocks(requester.UserID, blocks); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := map[string]any{
+		"imported": len(blocks),
+		"from":     from,
+		"to":       to,
+	}
+	if err := app.writeJSON(w, http.StatusOK, response, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) ListMyBusyBlocksHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	from, to, err := app.readTimeRange(r.URL.Query(), maxResolveSpan)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	blocks, err := app.models.Availability.GetBusyBlocks(requester.UserID, from, to)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"busy_blocks": blocks}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) DeleteMyBusyBlocksHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	if err := app.models.Availability.ReplaceBusyBlocks(requester.UserID, nil); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusNoContent, nil, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
@@ -0,0 +1,134 @@
+package application
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jonathanhu237/when-works/backend/internal/ical"
+	"github.com/jonathanhu237/when-works/backend/internal/models"
+)
+
+// The feed covers recent history so that just-finished shifts do not vanish
+// from calendars, plus the coming year.
+const (
+	calendarFeedPast  = 30 * 24 * time.Hour
+	calendarFeedAhead = 365 * 24 * time.Hour
+)
+
+// RotateMyCalendarTokenHandler issues a new subscription URL. Any previous URL
+// stops working, which is how users cut off a leaked link.
+func (app *Application) RotateMyCalendarTokenHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	token, err := app.models.User.RotateCalendarToken(requester.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.errorResponse(w, r, http.StatusNotFound, "USER_NOT_FOUND", "user not found", nil)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	url := strings.TrimRight(app.config.Server.PublicURL, "/") + "/v1/calendar/" + token + ".ics"
+	if err := app.writeJSON(w, http.StatusCreated, map[string]any{"calendar_url": url}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) RevokeMyCalendarTokenHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	if err := app.models.User.RevokeCalendarToken(requester.UserID); err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.errorResponse(w, r, http.StatusNotFound, "USER_NOT_FOUND", "user not found", nil)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusNoContent, nil, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// CalendarFeedHandler serves the user's shifts and confirmed meetings as an
+// iCalendar feed. Calendar clients cannot send cookies, so the secret token
+// in the URL is the only credential.
+func (app *Application) CalendarFeedHandler(w http.ResponseWriter, r *http.Request) {
+	token := chi.URLParam(r, "token")
+
+	user, err := app.models.User.GetByCalendarToken(token)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.errorResponse(w, r, http.StatusNotFound, "CALENDAR_NOT_FOUND", "calendar not found", nil)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	now := time.Now().UTC()
+	from, to := now.Add(-calendarFeedPast), now.Add(calendarFeedAhead)
+
+	shifts, err := app.models.Shift.GetForUser(user.ID, from, to)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	meetings, err := app.models.Poll.GetMeetingsForUser(user.ID, from, to)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	calendar := &ical.Calendar{
+		ProdID:          "-//WhenWorks//Calendar Feed//EN",
+		Name:            "WhenWorks - " + user.Name,
+		RefreshInterval: time.Hour,
+	}
+
+	for _, shift := range shifts {
+		event := ical.Event{
+			UID:          "shift-" + shift.ID.String() + "@when-works",
+			Summary:      shift.Title,
+			Start:        shift.StartAt,
+			End:          shift.EndAt,
+			Stamp:        shift.UpdatedAt,
+			LastModified: shift.UpdatedAt,
+		}
+		if shift.Tag != nil {
+			event.Description = "Tag: " + *shift.Tag
+		}
+		calendar.Events = append(calendar.Events, event)
+	}
+
+	for _, meeting := range meetings {
+		calendar.Events = append(calendar.Events, ical.Event{
+			UID:          "poll-" + meeting.PollID.String() + "@when-works",
+			Summary:      meeting.Title,
+			Description:  meeting.Description,
+			Start:        meeting.StartAt,
+			End:          meeting.EndAt,
+			Stamp:        meeting.UpdatedAt,
+			LastModified: meeting.UpdatedAt,
+		})
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="when-works.ics"`)
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	w.WriteHeader(http.StatusOK)
+
+	if err := ical.Encode(w, calendar); err != nil {
+		app.logger.Error("failed to write calendar feed", "error", err)
+	}
+}
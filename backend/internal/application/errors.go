@@ -1,10 +1,12 @@
 package application
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/jonathanhu237/when-works/backend/internal/models"
 )
 
 func (app *Application) logError(r *http.Request, err error) {
@@ -66,6 +68,74 @@ func (app *Application) unauthorizedResponse(w http.ResponseWriter, r *http.Requ
 	app.errorResponse(w, r, http.StatusUnauthorized, "UNAUTHORIZED", "you must be authenticated to access this resource", nil)
 }
 
+func (app *Application) invalidTokenResponse(w http.ResponseWriter, r *http.Request) {
+	app.errorResponse(w, r, http.StatusBadRequest, "INVALID_TOKEN", "the token is invalid, expired or has already been used", nil)
+}
+
+func (app *Application) userInvitedResponse(w http.ResponseWriter, r *http.Request) {
+	app.errorResponse(w, r, http.StatusConflict, "USER_INVITED", "the user has not accepted the invitation yet", nil)
+}
+
 func (app *Application) forbiddenResponse(w http.ResponseWriter, r *http.Request) {
 	app.errorResponse(w, r, http.StatusForbidden, "FORBIDDEN", "you do not have permission to access this resource", nil)
 }
+
+func (app *Application) shiftFullResponse(w http.ResponseWriter, r *http.Request) {
+	app.errorResponse(w, r, http.StatusConflict, "SHIFT_FULL", "the shift already has its required head-count", nil)
+}
+
+func (app *Application) alreadyAssignedResponse(w http.ResponseWriter, r *http.Request) {
+	app.errorResponse(w, r, http.StatusConflict, "SHIFT_ALREADY_ASSIGNED", "the user is already assigned to this shift", nil)
+}
+
+func (app *Application) doubleBookingResponse(w http.ResponseWriter, r *http.Request) {
+	app.errorResponse(w, r, http.StatusConflict, "SHIFT_DOUBLE_BOOKED", "the user is already assigned to an overlapping shift", nil)
+}
+
+func (app *Application) outsideAvailabilityResponse(w http.ResponseWriter, r *http.Request) {
+	app.errorResponse(w, r, http.StatusConflict, "SHIFT_OUTSIDE_AVAILABILITY", "the shift falls outside the user's availability", nil)
+}
+
+func (app *Application) userInactiveResponse(w http.ResponseWriter, r *http.Request) {
+	app.errorResponse(w, r, http.StatusConflict, "USER_INACTIVE", "the user is deactivated or archived", nil)
+}
+
+// assignmentConflictResponse lists the existing assignments a change would
+// leave breaking the staffing rules, each with the code of the broken rule.
+func (app *Application) assignmentConflictResponse(w http.ResponseWriter, r *http.Request, conflicts []models.AssignmentConflict) {
+	app.errorResponse(w, r, http.StatusConflict, "SHIFT_ASSIGNMENT_CONFLICT", "the change conflicts with existing assignments", map[string]any{
+		"conflicts": assignmentConflictDetails(conflicts),
+	})
+}
+
+func assignmentConflictDetails(conflicts []models.AssignmentConflict) []map[string]any {
+	details := make([]map[string]any, len(conflicts))
+	for i, conflict := range conflicts {
+		details[i] = map[string]any{
+			"shift_id": conflict.ShiftID,
+			"user_id":  conflict.UserID,
+			"code":     assignmentErrorCode(conflict.Err),
+		}
+	}
+	return details
+}
+
+// assignmentErrorCode is the error code of a staffing rule error of the models.
+func assignmentErrorCode(err error) string {
+	switch {
+	case errors.Is(err, models.ErrShiftFull):
+		return "SHIFT_FULL"
+	case errors.Is(err, models.ErrAlreadyAssigned):
+		return "SHIFT_ALREADY_ASSIGNED"
+	case errors.Is(err, models.ErrDoubleBooking):
+		return "SHIFT_DOUBLE_BOOKED"
+	case errors.Is(err, models.ErrOutsideAvailability):
+		return "SHIFT_OUTSIDE_AVAILABILITY"
+	case errors.Is(err, models.ErrUserInactive):
+		return "USER_INACTIVE"
+	case errors.Is(err, models.ErrRecordNotFound):
+		return "SHIFT_OR_USER_NOT_FOUND"
+	default:
+		return "SHIFT_ASSIGNMENT_CONFLICT"
+	}
+}
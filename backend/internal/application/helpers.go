@@ -7,8 +7,15 @@ import (
 	"io"
 	"maps"
 	"math/rand/v2"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 )
 
 // ------------------------------------
@@ -92,6 +99,89 @@ func (app *Application) readJSON(w http.ResponseWriter, r *http.Request, dst any
 	return nil
 }
 
+// ------------------------------------
+// Client
+// ------------------------------------
+
+// clientIP returns the address of the client. Proxy headers are only honoured
+// when the server is configured to trust them, since clients can forge them.
+func (app *Application) clientIP(r *http.Request) string {
+	if app.config.Server.TrustProxyHeaders {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			first, _, _ := strings.Cut(forwarded, ",")
+			return strings.TrimSpace(first)
+		}
+		if realIP := r.Header.Get("X-Real-IP"); realIP != "" {
+			return strings.TrimSpace(realIP)
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ------------------------------------
+// URL parameters
+// ------------------------------------
+func (app *Application) readUUIDParam(r *http.Request, param string, label string) (uuid.UUID, error) {
+	value := chi.URLParam(r, param)
+	if value == "" {
+		return uuid.Nil, fmt.Errorf("%s is required", label)
+	}
+
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid %s", label)
+	}
+
+	return id, nil
+}
+
+func (app *Application) readIntParam(r *http.Request, param string, label string) (int, error) {
+	value := chi.URLParam(r, param)
+	if value == "" {
+		return 0, fmt.Errorf("%s is required", label)
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid %s", label)
+	}
+
+	return n, nil
+}
+
+// ------------------------------------
+// Query string
+// ------------------------------------
+
+// readTimeRange parses the "from" and "to" RFC 3339 query parameters and makes
+// sure they describe a non-empty range no longer than maxSpan.
+func (app *Application) readTimeRange(qs url.Values, maxSpan time.Duration) (time.Time, time.Time, error) {
+	from, err := time.Parse(time.RFC3339, qs.Get("from"))
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("from must be an RFC 3339 timestamp")
+	}
+
+	to, err := time.Parse(time.RFC3339, qs.Get("to"))
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("to must be an RFC 3339 timestamp")
+	}
+
+	if !to.After(from) {
+		return time.Time{}, time.Time{}, errors.New("to must be after from")
+	}
+
+	if to.Sub(from) > maxSpan {
+		return time.Time{}, time.Time{}, fmt.Errorf("range must not exceed %d days", int(maxSpan.Hours()/24))
+	}
+
+	return from.UTC(), to.UTC(), nil
+}
+
 // ------------------------------------
 // Password
 // ------------------------------------
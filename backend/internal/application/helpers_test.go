@@ -0,0 +1,172 @@
+package application
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/jonathanhu237/when-works/backend/internal/config"
+	"github.com/jonathanhu237/when-works/backend/internal/lockout"
+	"github.com/jonathanhu237/when-works/backend/internal/mailer"
+	"github.com/jonathanhu237/when-works/backend/internal/models"
+	"github.com/jonathanhu237/when-works/backend/internal/ratelimit"
+	"github.com/jonathanhu237/when-works/backend/internal/testdb"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const testPassword = "correct horse battery staple"
+
+// newTestApplication returns an application backed by a fresh test database.
+// Rate limits are off so tests can send as many requests as they need.
+func newTestApplication(t *testing.T) *Application {
+	t.Helper()
+
+	cfg := config.Config{
+		Environment: config.Development,
+		Database:    config.DatabaseConfig{QueryTimeout: 5},
+		JWT:         config.JWTConfig{Secret: "test-secret", Expiration: 900, RefreshExpiration: 3600},
+		SMTP:        config.SMTPConfig{Host: "localhost", Port: 465, Timeout: 1},
+		Lockout:     config.LockoutConfig{UsernameThreshold: 5, IPThreshold: 20, BaseDelay: 30, MaxDelay: 3600, Window: 86400},
+	}
+
+	m, err := mailer.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := New(
+		cfg,
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+		models.New(testdb.New(t), cfg),
+		validator.New(validator.WithRequiredStructEnabled()),
+		m,
+		lockout.NewMemoryStore(),
+		ratelimit.NewMemoryStore(),
+	)
+	if err := app.registerValidations(); err != nil {
+		t.Fatal(err)
+	}
+
+	return app
+}
+
+// createOrganization creates an organization with a superadmin who can sign
+// in with username and testPassword.
+func createOrganization(t *testing.T, app *Application, name, username string) (*models.Organization, *models.User) {
+	t.Helper()
+
+	org := &models.Organization{Name: name}
+	admin := newTestUser(t, username)
+	if err := app.models.Organization.Insert(org, admin); err != nil {
+		t.Fatalf("insert organization %s: %v", name, err)
+	}
+
+	return org, admin
+}
+
+// createUser adds an active user to the organization and gives them the
+// built-in roles.
+func createUser(t *testing.T, app *Application, orgID uuid.UUID, username string, roles ...string) *models.User {
+	t.Helper()
+
+	user := newTestUser(t, username)
+	user.OrgID = orgID
+	if err := app.models.User.Insert(user); err != nil {
+		t.Fatalf("insert user %s: %v", username, err)
+	}
+	for _, role := range roles {
+		if err := app.models.Role.AssignByName(user.ID, role); err != nil {
+			t.Fatalf("assign %s to %s: %v", role, username, err)
+		}
+	}
+
+	return user
+}
+
+func newTestUser(t *testing.T, username string) *models.User {
+	t.Helper()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &models.User{
+		Username:     username,
+		Email:        username + "@example.com",
+		Name:         username,
+		PasswordHash: string(hash),
+		Status:       models.UserStatusActive,
+	}
+}
+
+// login signs the user in and returns the session cookies.
+func login(t *testing.T, app *Application, username string) []*http.Cookie {
+	t.Helper()
+
+	body := jsonBody(t, map[string]string{"username": username, "password": testPassword})
+	res := serve(app, http.MethodPost, "/v1/auth/login", body, nil)
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("login as %s: status %d", username, res.StatusCode)
+	}
+
+	return res.Cookies()
+}
+
+// jsonBody encodes v as a request body.
+func jsonBody(t *testing.T, v any) io.Reader {
+	t.Helper()
+
+	body, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return bytes.NewReader(body)
+}
+
+// serve sends the request through the application's routes.
+func serve(app *Application, method, path string, body io.Reader, cookies []*http.Cookie) *http.Response {
+	req := httptest.NewRequest(method, path, body)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+
+	rec := httptest.NewRecorder()
+	app.routes().ServeHTTP(rec, req)
+
+	return rec.Result()
+}
+
+// makeAvailable lets the user work around the clock on every day of the week.
+func makeAvailable(t *testing.T, app *Application, userID uuid.UUID) {
+	t.Helper()
+
+	availability := &models.Availability{TimeZone: "UTC"}
+	for day := range 7 {
+		availability.Windows = append(availability.Windows, models.AvailabilityWindow{DayOfWeek: day, StartTime: "00:00", EndTime: "24:00"})
+	}
+	if err := app.models.Availability.Replace(userID, availability); err != nil {
+		t.Fatalf("set availability: %v", err)
+	}
+}
+
+// serveWithToken sends the request with a personal access token.
+func serveWithToken(app *Application, method, path, token string) *http.Response {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	rec := httptest.NewRecorder()
+	app.routes().ServeHTTP(rec, req)
+
+	return rec.Result()
+}
@@ -1,13 +1,20 @@
 package application
 
 import (
+	"errors"
+
 	"github.com/jonathanhu237/when-works/backend/internal/models"
 	"golang.org/x/crypto/bcrypt"
 )
 
 func (app *Application) Init() error {
+	// Register custom validations
+	if err := app.registerValidations(); err != nil {
+		return err
+	}
+
 	// Check if an admin user already exists
-	adminExists, err := app.models.User.AdminExists()
+	adminExists, err := app.models.User.SuperadminExists()
 	if err != nil {
 		return err
 	}
@@ -23,7 +30,7 @@ func (app *Application) Init() error {
 		Username: app.config.InitialAdmin.Username,
 		Email:    app.config.InitialAdmin.Email,
 		Name:     "Admin",
-		IsAdmin:  true,
+		Status:   models.UserStatusActive,
 	}
 
 	// Hash the password
@@ -34,10 +41,27 @@ func (app *Application) Init() error {
 
 	initialAdmin.PasswordHash = string(passwordHash)
 
+	// A database that held data before organizations were introduced already
+	// has the host organization; otherwise it is created with the admin
+	host, err := app.models.Organization.GetHost()
+	if err != nil {
+		if !errors.Is(err, models.ErrRecordNotFound) {
+			return err
+		}
+
+		app.logger.Info("creating host organization", "name", app.config.InitialAdmin.Organization)
+		return app.models.Organization.Insert(&models.Organization{Name: app.config.InitialAdmin.Organization, Host: true}, &initialAdmin)
+	}
+
 	// Insert the initial admin user into the database
+	initialAdmin.OrgID = host.ID
 	if err := app.models.User.Insert(&initialAdmin); err != nil {
 		return err
 	}
 
+	if err := app.models.Role.AssignByName(initialAdmin.ID, models.RoleSuperadmin); err != nil {
+		return err
+	}
+
 	return nil
 }
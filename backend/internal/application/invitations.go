@@ -0,0 +1,153 @@
+package application
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jonathanhu237/when-works/backend/internal/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const activationTTL = 7 * 24 * time.Hour
+
+// sendInvitation issues a fresh activation token for an invited user, which
+// replaces any earlier one, and emails the activation link in the background.
+func (app *Application) sendInvitation(user *models.User) error {
+	token, err := app.models.Token.New(user.ID, activationTTL, models.ScopeActivation)
+	if err != nil {
+		return err
+	}
+
+	app.background(func() {
+		data := map[string]any{
+			"name":     user.Name,
+			"username": user.Username,
+			"link":     strings.TrimRight(app.config.Frontend.URL, "/") + "/activate?token=" + url.QueryEscape(token),
+			"days":     int(activationTTL.Hours() / 24),
+		}
+
+		if err := app.mailer.SendHTML(user.Email, "Welcome to WhenWorks", "welcome.html", data); err != nil {
+			app.logger.Error("failed to send invitation email", "error", err, "email", user.Email)
+			return
+		}
+		app.logger.Info("invitation email sent", "email", user.Email)
+	})
+
+	return nil
+}
+
+func (app *Application) ResendInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := app.readUUIDParam(r, "userID", "user id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user, err := app.models.User.GetByID(userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.errorResponse(w, r, http.StatusNotFound, "USER_NOT_FOUND", "user not found", nil)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if user.Status != models.UserStatusInvited {
+		app.errorResponse(w, r, http.StatusConflict, "USER_ALREADY_ACTIVE", "the user has already accepted the invitation", nil)
+		return
+	}
+
+	if err := app.sendInvitation(user); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusNoContent, nil, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// RevokeInvitationHandler withdraws a pending invitation. The invited user has
+// never signed in and owns nothing, so it is deleted outright.
+func (app *Application) RevokeInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := app.readUUIDParam(r, "userID", "user id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user, err := app.models.User.GetByID(userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.errorResponse(w, r, http.StatusNotFound, "USER_NOT_FOUND", "user not found", nil)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if user.Status != models.UserStatusInvited {
+		app.errorResponse(w, r, http.StatusConflict, "USER_ALREADY_ACTIVE", "the user has already accepted the invitation", nil)
+		return
+	}
+
+	if err := app.models.User.DeleteInvited(user.ID); err != nil {
+		switch {
+		// Accepted between the read and the delete
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.errorResponse(w, r, http.StatusConflict, "USER_ALREADY_ACTIVE", "the user has already accepted the invitation", nil)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusNoContent, nil, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// ActivateAccountHandler accepts an invitation: the user picks their password
+// and the account becomes active. They sign in normally afterwards.
+func (app *Application) ActivateAccountHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Token    string `json:"token" validate:"required"`
+		Password string `json:"password" validate:"required,min=8,max=72,bcrypt"`
+	}
+
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.validator.Struct(input); err != nil {
+		app.failedValidationResponse(w, r, err)
+		return
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.models.User.Activate(input.Token, string(passwordHash)); err != nil {
+		switch {
+		case errors.Is(err, models.ErrInvalidToken), errors.Is(err, models.ErrRecordNotFound):
+			app.invalidTokenResponse(w, r)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusNoContent, nil, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
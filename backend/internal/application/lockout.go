@@ -0,0 +1,105 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jonathanhu237/when-works/backend/internal/models"
+)
+
+func (app *Application) loginLockedResponse(w http.ResponseWriter, r *http.Request, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+
+	details := map[string]any{"retry_after": seconds}
+	app.errorResponse(w, r, http.StatusTooManyRequests, "LOGIN_LOCKED", "too many failed login attempts, try again later", details)
+}
+
+// loginLockedFor returns how long logins for the username from the IP remain
+// locked out, zero if they are allowed.
+func (app *Application) loginLockedFor(ctx context.Context, username, ip string) (time.Duration, error) {
+	usernameWait, err := app.usernameLockout.Check(ctx, strings.ToLower(username))
+	if err != nil {
+		return 0, err
+	}
+
+	ipWait, err := app.ipLockout.Check(ctx, ip)
+	if err != nil {
+		return 0, err
+	}
+
+	return max(usernameWait, ipWait), nil
+}
+
+// loginFailed counts a failed login against the username and the IP and
+// returns the lockout it triggered, if any. Unknown usernames are counted
+// too so their responses look like those of real accounts.
+func (app *Application) loginFailed(ctx context.Context, username, ip string) (time.Duration, error) {
+	usernameWait, err := app.usernameLockout.Fail(ctx, strings.ToLower(username))
+	if err != nil {
+		return 0, err
+	}
+
+	ipWait, err := app.ipLockout.Fail(ctx, ip)
+	if err != nil {
+		return 0, err
+	}
+
+	if usernameWait > 0 || ipWait > 0 {
+		app.logger.Warn("login locked out after repeated failures", "username", username, "ip", ip)
+	}
+
+	return max(usernameWait, ipWait), nil
+}
+
+// invalidLoginResponse records the failure and answers with invalid
+// credentials, or with the lockout if this failure triggered one.
+func (app *Application) invalidLoginResponse(w http.ResponseWriter, r *http.Request, username string) {
+	wait, err := app.loginFailed(r.Context(), username, app.clientIP(r))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if wait > 0 {
+		app.loginLockedResponse(w, r, wait)
+		return
+	}
+
+	app.invalidCredentialsResponse(w, r)
+}
+
+// UnlockUserHandler clears the failed logins and lockout of a user's
+// username. Lockouts of client IPs expire on their own.
+func (app *Application) UnlockUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := app.readUUIDParam(r, "userID", "user id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user, err := app.models.User.GetByID(userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.errorResponse(w, r, http.StatusNotFound, "USER_NOT_FOUND", "user not found", nil)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.usernameLockout.Reset(r.Context(), strings.ToLower(user.Username)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusNoContent, nil, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
@@ -90,7 +90,7 @@ func (app *Application) UpdateMePasswordHandler(w http.ResponseWriter, r *http.R
 
 	var input struct {
 		OldPassword string `json:"old_password" validate:"required"`
-		NewPassword string `json:"new_password" validate:"required,min=8"`
+		NewPassword string `json:"new_password" validate:"required,min=8,max=72,bcrypt"`
 	}
 
 	if err := app.readJSON(w, r, &input); err != nil {
@@ -139,6 +139,17 @@ func (app *Application) UpdateMePasswordHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
+	// Sign out every other device and drop the access tokens, in case the old
+	// password was compromised
+	if err := app.models.Session.RevokeAllForUser(requester.UserID, requester.SessionID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if err := app.models.APIToken.RevokeAllForUser(requester.UserID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
 	if err := app.writeJSON(w, http.StatusNoContent, nil, nil); err != nil {
 		app.internalServerError(w, r, err)
 	}
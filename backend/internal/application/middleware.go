@@ -2,10 +2,18 @@ package application
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"github.com/jonathanhu237/when-works/backend/internal/config"
+	"github.com/jonathanhu237/when-works/backend/internal/models"
+	"github.com/jonathanhu237/when-works/backend/internal/ratelimit"
 )
 
 type contextKey string
@@ -15,65 +23,337 @@ const requesterContextKey = contextKey("requester")
 type RequesterInfo struct {
 	UserID   uuid.UUID
 	Username string
-	IsAdmin  bool
+	// OrgID is the organization of the user. Every query the requester makes
+	// is limited to it.
+	OrgID uuid.UUID
+	// Permissions are those of the user's roles at the time of the request.
+	Permissions []string
+	// ManagedTeamIDs are the teams the user manages, whose members they may
+	// see and schedule without holding the permissions for everyone.
+	ManagedTeamIDs []uuid.UUID
+	// Superadmin and TOTPEnabled come with the principal, so the two-factor
+	// requirement for admins costs no query of its own.
+	Superadmin  bool
+	TOTPEnabled bool
+	SessionID   uuid.UUID
+	// APIToken is set when the request was made with a personal access
+	// token rather than a login session.
+	APIToken *models.APIToken
 }
 
-// requireAuth middleware validates JWT token and sets user in context
+// Can reports whether the requester holds the permission.
+func (ri *RequesterInfo) Can(permission string) bool {
+	return slices.Contains(ri.Permissions, permission)
+}
+
+// Scope returns the users the requester may act on under the permission: all
+// of them if they hold it, otherwise the members of the teams they manage.
+func (ri *RequesterInfo) Scope(permission string) models.UserScope {
+	if ri.Can(permission) {
+		return models.AllUsers(ri.OrgID)
+	}
+	return models.TeamMembers(ri.OrgID, ri.ManagedTeamIDs)
+}
+
+var errUnauthenticated = errors.New("unauthenticated")
+
+// requireAuth middleware authenticates the request by a personal access token
+// in the Authorization header or else by the session cookies, and sets the
+// requester in context
 func (app *Application) requireAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Get token from cookie
-		cookie, err := r.Cookie("accessToken")
+		var requester *RequesterInfo
+		var err error
+
+		if header := r.Header.Get("Authorization"); header != "" {
+			requester, err = app.authenticateAPIToken(header)
+		} else {
+			requester, err = app.authenticateSession(r)
+		}
 		if err != nil {
-			app.unauthorizedResponse(w, r)
+			switch {
+			case errors.Is(err, errUnauthenticated):
+				app.unauthorizedResponse(w, r)
+			default:
+				app.internalServerError(w, r, err)
+			}
 			return
 		}
 
-		// Parse and validate token
-		tokenString := cookie.Value
-		token, err := jwt.ParseWithClaims(tokenString, &CustomClaims{}, func(token *jwt.Token) (any, error) {
-			return []byte(app.config.JWT.Secret), nil
-		})
+		// Set requester in context
+		ctx := context.WithValue(r.Context(), requesterContextKey, requester)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
 
-		if err != nil {
-			app.unauthorizedResponse(w, r)
-			return
+// authenticateSession validates the access token cookie and its session.
+func (app *Application) authenticateSession(r *http.Request) (*RequesterInfo, error) {
+	// Get token from cookie
+	cookie, err := r.Cookie("accessToken")
+	if err != nil {
+		return nil, errUnauthenticated
+	}
+
+	// Parse and validate token
+	claims, err := app.parseAccessToken(cookie.Value)
+	if err != nil {
+		return nil, errUnauthenticated
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return nil, errUnauthenticated
+	}
+
+	// Reject tokens of sessions that have been logged out or revoked
+	sessionID, err := uuid.Parse(claims.SessionID)
+	if err != nil {
+		return nil, errUnauthenticated
+	}
+
+	// The user is read back with the session so that role changes and
+	// deletions take effect without waiting for the token to expire
+	principal, err := app.models.Session.Touch(sessionID)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			return nil, errUnauthenticated
+		default:
+			return nil, err
 		}
+	}
+	if principal.UserID != userID || claims.OrgID != principal.OrgID.String() {
+		return nil, errUnauthenticated
+	}
 
-		// Extract claims
-		claims, ok := token.Claims.(*CustomClaims)
-		if !ok {
-			app.unauthorizedResponse(w, r)
-			return
+	return &RequesterInfo{
+		UserID:         principal.UserID,
+		Username:       principal.Username,
+		OrgID:          principal.OrgID,
+		Permissions:    principal.Permissions,
+		ManagedTeamIDs: principal.ManagedTeamIDs,
+		Superadmin:     principal.Superadmin,
+		TOTPEnabled:    principal.TOTPEnabled,
+		SessionID:      sessionID,
+	}, nil
+}
+
+// authenticateAPIToken validates a personal access token sent as
+// "Authorization: Bearer <token>".
+func (app *Application) authenticateAPIToken(header string) (*RequesterInfo, error) {
+	scheme, plaintext, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || plaintext == "" {
+		return nil, errUnauthenticated
+	}
+
+	token, principal, err := app.models.APIToken.Authenticate(plaintext)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrInvalidToken):
+			return nil, errUnauthenticated
+		default:
+			return nil, err
 		}
+	}
+
+	return &RequesterInfo{
+		UserID:         principal.UserID,
+		Username:       principal.Username,
+		OrgID:          principal.OrgID,
+		Permissions:    principal.Permissions,
+		ManagedTeamIDs: principal.ManagedTeamIDs,
+		Superadmin:     principal.Superadmin,
+		TOTPEnabled:    principal.TOTPEnabled,
+		APIToken:       token,
+	}, nil
+}
+
+// requirePermission middleware ensures the user's roles currently grant the
+// permission, and that the user has two-factor authentication enabled if the
+// configuration demands it for admins
+func (app *Application) requirePermission(permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+			if !requester.Can(permission) {
+				app.forbiddenResponse(w, r)
+				return
+			}
+
+			if !app.checkAdminTOTP(w, r, requester) {
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// requirePermissionOrManager middleware is requirePermission for routes that
+// team managers may also use on their own members. Handlers narrow what
+// managers reach with RequesterInfo.Scope.
+func (app *Application) requirePermissionOrManager(permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+			if !requester.Can(permission) && len(requester.ManagedTeamIDs) == 0 {
+				app.forbiddenResponse(w, r)
+				return
+			}
+
+			if !app.checkAdminTOTP(w, r, requester) {
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
 
-		userID, err := uuid.Parse(claims.UserID)
+// requireHostOrganization middleware limits routes that reach across
+// organizations to members of the host organization.
+func (app *Application) requireHostOrganization(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+		org, err := app.models.Organization.GetByID(requester.OrgID)
 		if err != nil {
-			app.unauthorizedResponse(w, r)
+			app.internalServerError(w, r, err)
 			return
 		}
-
-		// Set requester in context
-		requester := &RequesterInfo{
-			UserID:   userID,
-			Username: claims.Username,
-			IsAdmin:  claims.IsAdmin,
+		if !org.Host {
+			app.forbiddenResponse(w, r)
+			return
 		}
 
-		ctx := context.WithValue(r.Context(), requesterContextKey, requester)
-		next.ServeHTTP(w, r.WithContext(ctx))
+		next.ServeHTTP(w, r)
 	})
 }
 
-// requireAdmin middleware ensures the user is an admin
-func (app *Application) requireAdmin(next http.Handler) http.Handler {
+// requireUserInScope middleware guards routes about the user in the userID
+// URL parameter. Requesters only reach the users of their organization, and
+// without the permission only the members of the teams they manage; anyone
+// else is reported as not found. Archived users pass, so that they can be
+// restored and purged; the other handlers do not find them.
+func (app *Application) requireUserInScope(permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+			userID, err := app.readUUIDParam(r, "userID", "user id")
+			if err != nil {
+				app.badRequestResponse(w, r, err)
+				return
+			}
+
+			ok, err := app.models.User.InScope(userID, requester.Scope(permission).WithArchived())
+			if err != nil {
+				app.internalServerError(w, r, err)
+				return
+			}
+			if !ok {
+				app.errorResponse(w, r, http.StatusNotFound, "USER_NOT_FOUND", "user not found", nil)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// checkAdminTOTP ensures a superadmin requester has two-factor
+// authentication enabled if the configuration demands it. Other roles are not
+// affected. When it returns false an error response has already been written.
+func (app *Application) checkAdminTOTP(w http.ResponseWriter, r *http.Request, requester *RequesterInfo) bool {
+	if !app.config.TOTP.RequireForAdmins || !requester.Superadmin {
+		return true
+	}
+
+	if !requester.TOTPEnabled {
+		app.errorResponse(w, r, http.StatusForbidden, "TOTP_REQUIRED", "admins must enable two-factor authentication", nil)
+		return false
+	}
+
+	return true
+}
+
+// requireScope middleware limits requests made with a personal access token
+// to route groups one of the scopes covers. Read-only tokens may make safe
+// requests anywhere. Requests made with a login session are not limited.
+func (app *Application) requireScope(scopes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+			if token := requester.APIToken; token != nil {
+				safe := r.Method == http.MethodGet || r.Method == http.MethodHead
+				if !token.HasScope(scopes...) && !(safe && token.HasScope(models.APIScopeRead)) {
+					app.errorResponse(w, r, http.StatusForbidden, "INSUFFICIENT_SCOPE", "the access token does not allow this request", nil)
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// requireSession middleware refuses requests made with a personal access
+// token, for account settings that only the user themselves should change,
+// such as the tokens themselves.
+func (app *Application) requireSession(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
 
-		if !requester.IsAdmin {
-			app.forbiddenResponse(w, r)
+		if requester.APIToken != nil {
+			app.errorResponse(w, r, http.StatusForbidden, "SESSION_REQUIRED", "this request cannot be made with an access token", nil)
 			return
 		}
 
 		next.ServeHTTP(w, r)
 	})
 }
+
+// rateLimit limits the requests of a route group. Signed-in requesters are
+// counted per user when it runs after requireAuth, and everyone else per
+// client IP. Authenticated groups use it on both sides of requireAuth, so
+// requests that fail authentication are limited as well. Each group has its
+// own buckets.
+func (app *Application) rateLimit(group string, limit config.RateLimit) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !limit.Enabled() {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := group + ":ip:" + app.clientIP(r)
+			if requester, ok := r.Context().Value(requesterContextKey).(*RequesterInfo); ok {
+				key = group + ":user:" + requester.UserID.String()
+			}
+
+			result, err := app.rateLimitStore.Take(r.Context(), key, ratelimit.Limit{Requests: limit.Requests, Period: limit.Period})
+			if err != nil {
+				app.internalServerError(w, r, err)
+				return
+			}
+
+			w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Requests, int(limit.Period.Seconds())))
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(int(math.Ceil(result.ResetAfter.Seconds()))))
+
+			if !result.Allowed {
+				seconds := int(math.Ceil(result.RetryAfter.Seconds()))
+				w.Header().Set("Retry-After", strconv.Itoa(seconds))
+
+				details := map[string]any{"retry_after": seconds}
+				app.errorResponse(w, r, http.StatusTooManyRequests, "RATE_LIMITED", "too many requests, try again later", details)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
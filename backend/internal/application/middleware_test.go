@@ -0,0 +1,74 @@
+package application
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestRoleChangesApplyToNextRequest(t *testing.T) {
+	app := newTestApplication(t)
+	org, _ := createOrganization(t, app, "Acme", "owner")
+	auditor := createUser(t, app, org.ID, "auditor", "auditor")
+
+	cookies := login(t, app, "auditor")
+	if res := serve(app, http.MethodGet, "/v1/users", nil, cookies); res.StatusCode != http.StatusOK {
+		t.Fatalf("before the role is revoked: status %d, want %d", res.StatusCode, http.StatusOK)
+	}
+
+	if err := app.models.Role.SetUserRoles(org.ID, auditor.ID, nil); err != nil {
+		t.Fatal(err)
+	}
+	if res := serve(app, http.MethodGet, "/v1/users", nil, cookies); res.StatusCode != http.StatusForbidden {
+		t.Fatalf("after the role is revoked: status %d, want %d", res.StatusCode, http.StatusForbidden)
+	}
+
+	if err := app.models.User.Deactivate(org.ID, auditor.ID); err != nil {
+		t.Fatal(err)
+	}
+	if res := serve(app, http.MethodGet, "/v1/users", nil, cookies); res.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("after the user is deactivated: status %d, want %d", res.StatusCode, http.StatusUnauthorized)
+	}
+}
+
+func TestSessionRejectsTokenOfAnotherOrganization(t *testing.T) {
+	app := newTestApplication(t)
+	createOrganization(t, app, "A", "a-admin")
+	orgB, _ := createOrganization(t, app, "B", "b-admin")
+
+	cookies := login(t, app, "a-admin")
+
+	var access *http.Cookie
+	for _, cookie := range cookies {
+		if cookie.Name == "accessToken" {
+			access = cookie
+		}
+	}
+	if access == nil {
+		t.Fatal("login set no access token cookie")
+	}
+
+	claims, err := app.parseAccessToken(access.Value)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// withOrg signs the claims of the session again for the organization
+	withOrg := func(orgID string) []*http.Cookie {
+		forged := *claims
+		forged.OrgID = orgID
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, forged).SignedString([]byte(app.config.JWT.Secret))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return []*http.Cookie{{Name: "accessToken", Value: token}}
+	}
+
+	if res := serve(app, http.MethodGet, "/v1/me", nil, withOrg(claims.OrgID)); res.StatusCode != http.StatusOK {
+		t.Fatalf("token of the session's organization: status %d, want %d", res.StatusCode, http.StatusOK)
+	}
+	if res := serve(app, http.MethodGet, "/v1/me", nil, withOrg(orgB.ID.String())); res.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("token of another organization: status %d, want %d", res.StatusCode, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,88 @@
+package application
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/jonathanhu237/when-works/backend/internal/models"
+)
+
+func (app *Application) GetMyOrganizationHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	org, err := app.models.Organization.GetByID(requester.OrgID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"organization": org}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) ListOrganizationsHandler(w http.ResponseWriter, r *http.Request) {
+	orgs, err := app.models.Organization.GetAll()
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"organizations": orgs}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// CreateOrganizationHandler creates an organization and invites its first
+// superadmin, who manages it from then on.
+func (app *Application) CreateOrganizationHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name  string `json:"name" validate:"required,max=100"`
+		Admin struct {
+			Username string `json:"username" validate:"required"`
+			Email    string `json:"email" validate:"required,email"`
+			Name     string `json:"name" validate:"required"`
+		} `json:"admin"`
+	}
+
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.validator.Struct(input); err != nil {
+		app.failedValidationResponse(w, r, err)
+		return
+	}
+
+	org := &models.Organization{Name: input.Name}
+	admin := &models.User{
+		Username: input.Admin.Username,
+		Email:    input.Admin.Email,
+		Name:     input.Admin.Name,
+		Status:   models.UserStatusInvited,
+	}
+
+	if err := app.models.Organization.Insert(org, admin); err != nil {
+		switch {
+		case errors.Is(err, models.ErrOrganizationNameConflict):
+			app.errorResponse(w, r, http.StatusConflict, "ORGANIZATION_NAME_CONFLICT", "organization name already exists", nil)
+		case errors.Is(err, models.ErrUsernameConflict):
+			app.errorResponse(w, r, http.StatusConflict, "USER_USERNAME_CONFLICT", "username already exists", nil)
+		case errors.Is(err, models.ErrEmailConflict):
+			app.errorResponse(w, r, http.StatusConflict, "USER_EMAIL_CONFLICT", "email already exists", nil)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.sendInvitation(admin); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusCreated, map[string]any{"organization": org, "admin": admin}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
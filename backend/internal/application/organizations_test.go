@@ -0,0 +1,100 @@
+package application
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jonathanhu237/when-works/backend/internal/models"
+)
+
+// tenantIDs are the IDs of one record of each kind in an organization.
+type tenantIDs struct {
+	user, shift, schedule, team, poll, token uuid.UUID
+}
+
+func createTenantRecords(t *testing.T, app *Application, orgID, adminID uuid.UUID) tenantIDs {
+	t.Helper()
+
+	start := time.Date(2025, time.March, 3, 9, 0, 0, 0, time.UTC)
+
+	shift := &models.Shift{OrgID: orgID, Title: "Morning", StartAt: start, EndAt: start.Add(8 * time.Hour), RequiredHeadcount: 1}
+	if err := app.models.Shift.Insert(shift); err != nil {
+		t.Fatalf("insert shift: %v", err)
+	}
+
+	schedule := &models.Schedule{OrgID: orgID, Title: "March", PeriodStart: start, PeriodEnd: start.AddDate(0, 1, 0)}
+	if err := app.models.Schedule.Insert(schedule); err != nil {
+		t.Fatalf("insert schedule: %v", err)
+	}
+
+	team := &models.Team{OrgID: orgID, Name: "Kitchen"}
+	if err := app.models.Team.Insert(team); err != nil {
+		t.Fatalf("insert team: %v", err)
+	}
+
+	poll := &models.Poll{
+		OrgID:   orgID,
+		OwnerID: adminID,
+		Title:   "Team meeting",
+		Options: []models.PollOption{{StartAt: start, EndAt: start.Add(time.Hour)}},
+	}
+	if _, err := app.models.Poll.Insert(poll, nil); err != nil {
+		t.Fatalf("insert poll: %v", err)
+	}
+
+	token := &models.APIToken{UserID: adminID, Name: "CI", Scopes: []string{models.APIScopeRead}, ExpiresAt: time.Now().Add(time.Hour)}
+	if _, err := app.models.APIToken.Insert(token); err != nil {
+		t.Fatalf("insert API token: %v", err)
+	}
+
+	return tenantIDs{user: adminID, shift: shift.ID, schedule: schedule.ID, team: team.ID, poll: poll.ID, token: token.ID}
+}
+
+func TestOrganizationIsolation(t *testing.T) {
+	app := newTestApplication(t)
+
+	orgA, adminA := createOrganization(t, app, "A", "a-admin")
+	orgB, adminB := createOrganization(t, app, "B", "b-admin")
+	own := createTenantRecords(t, app, orgA.ID, adminA.ID)
+	foreign := createTenantRecords(t, app, orgB.ID, adminB.ID)
+
+	cookies := login(t, app, "a-admin")
+
+	tests := []struct {
+		name   string
+		method string
+		path   func(ids tenantIDs) string
+		ok     int
+	}{
+		{"user", http.MethodGet, func(ids tenantIDs) string { return "/v1/users/" + ids.user.String() }, http.StatusOK},
+		{"user sessions", http.MethodGet, func(ids tenantIDs) string { return "/v1/users/" + ids.user.String() + "/sessions" }, http.StatusOK},
+		{"shift", http.MethodGet, func(ids tenantIDs) string { return "/v1/shifts/" + ids.shift.String() }, http.StatusOK},
+		{"schedule", http.MethodGet, func(ids tenantIDs) string { return "/v1/schedules/" + ids.schedule.String() }, http.StatusOK},
+		{"team", http.MethodGet, func(ids tenantIDs) string { return "/v1/teams/" + ids.team.String() }, http.StatusOK},
+		{"poll", http.MethodGet, func(ids tenantIDs) string { return "/v1/polls/" + ids.poll.String() }, http.StatusOK},
+		{"token", http.MethodDelete, func(ids tenantIDs) string { return "/v1/me/tokens/" + ids.token.String() }, http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The foreign request goes first so that the token of the own
+			// organization is still there to compare against
+			if res := serve(app, tt.method, tt.path(foreign), nil, cookies); res.StatusCode != http.StatusNotFound {
+				t.Errorf("%s of organization B: status %d, want %d", tt.name, res.StatusCode, http.StatusNotFound)
+			}
+			if res := serve(app, tt.method, tt.path(own), nil, cookies); res.StatusCode != tt.ok {
+				t.Errorf("%s of organization A: status %d, want %d", tt.name, res.StatusCode, tt.ok)
+			}
+		})
+	}
+
+	tokens, err := app.models.APIToken.GetForUser(adminB.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != 1 {
+		t.Errorf("organization B has %d API tokens left, want 1", len(tokens))
+	}
+}
@@ -0,0 +1,122 @@
+package application
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jonathanhu237/when-works/backend/internal/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const passwordResetTTL = 30 * time.Minute
+
+// ForgotPasswordHandler emails a reset link if the address belongs to a user.
+// The response is the same either way, and the lookup happens in the
+// background so timing does not give the answer away either.
+func (app *Application) ForgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email string `json:"email" validate:"required,email"`
+	}
+
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.validator.Struct(input); err != nil {
+		app.failedValidationResponse(w, r, err)
+		return
+	}
+
+	app.background(func() {
+		user, err := app.models.User.GetByEmail(input.Email)
+		if err != nil {
+			if !errors.Is(err, models.ErrRecordNotFound) {
+				app.logger.Error("failed to look up user for password reset", "error", err)
+			}
+			return
+		}
+
+		// Invited users set their first password through the activation link
+		if user.Status != models.UserStatusActive {
+			return
+		}
+
+		token, err := app.models.Token.New(user.ID, passwordResetTTL, models.ScopePasswordReset)
+		if err != nil {
+			app.logger.Error("failed to create password reset token", "error", err, "user_id", user.ID)
+			return
+		}
+
+		data := map[string]any{
+			"name":    user.Name,
+			"link":    strings.TrimRight(app.config.Frontend.URL, "/") + "/reset-password?token=" + url.QueryEscape(token),
+			"minutes": int(passwordResetTTL.Minutes()),
+		}
+
+		if err := app.mailer.SendHTML(user.Email, "Reset Your WhenWorks Password", "password_reset_link.html", data); err != nil {
+			app.logger.Error("failed to send password reset link email", "error", err, "email", user.Email)
+			return
+		}
+		app.logger.Info("password reset link email sent", "email", user.Email)
+	})
+
+	message := "if an account with that email exists, a password reset link has been sent"
+	if err := app.writeJSON(w, http.StatusAccepted, map[string]any{"message": message}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// ResetPasswordHandler sets a new password using a reset token. The token is
+// only used up together with the password change, and every session of the
+// user is signed out.
+func (app *Application) ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Token       string `json:"token" validate:"required"`
+		NewPassword string `json:"new_password" validate:"required,min=8,max=72,bcrypt"`
+	}
+
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.validator.Struct(input); err != nil {
+		app.failedValidationResponse(w, r, err)
+		return
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(input.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	userID, err := app.models.User.ResetPassword(input.Token, string(passwordHash))
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrInvalidToken), errors.Is(err, models.ErrRecordNotFound):
+			app.invalidTokenResponse(w, r)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.models.Session.RevokeAllForUser(userID, uuid.Nil); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if err := app.models.APIToken.RevokeAllForUser(userID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusNoContent, nil, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
@@ -0,0 +1,36 @@
+package application
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jonathanhu237/when-works/backend/internal/models"
+)
+
+func TestResetPasswordRevokesAPITokens(t *testing.T) {
+	app := newTestApplication(t)
+	_, admin := createOrganization(t, app, "Acme", "owner")
+
+	token := &models.APIToken{UserID: admin.ID, Name: "CI", Scopes: []string{models.APIScopeRead}, ExpiresAt: time.Now().Add(time.Hour)}
+	plaintext, err := app.models.APIToken.Insert(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res := serveWithToken(app, http.MethodGet, "/v1/me", plaintext); res.StatusCode != http.StatusOK {
+		t.Fatalf("before the reset: status %d, want %d", res.StatusCode, http.StatusOK)
+	}
+
+	resetToken, err := app.models.Token.New(admin.ID, time.Hour, models.ScopePasswordReset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := map[string]string{"token": resetToken, "new_password": "a brand new password"}
+	if res := serve(app, http.MethodPost, "/v1/auth/reset-password", jsonBody(t, input), nil); res.StatusCode != http.StatusNoContent {
+		t.Fatalf("reset password: status %d, want %d", res.StatusCode, http.StatusNoContent)
+	}
+
+	if res := serveWithToken(app, http.MethodGet, "/v1/me", plaintext); res.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("after the reset: status %d, want %d", res.StatusCode, http.StatusUnauthorized)
+	}
+}
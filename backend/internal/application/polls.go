@@ -0,0 +1,489 @@
+package application
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jonathanhu237/when-works/backend/internal/models"
+)
+
+type pollOptionInput struct {
+	StartAt time.Time `json:"start_at" validate:"required"`
+	EndAt   time.Time `json:"end_at" validate:"required,gtfield=StartAt"`
+}
+
+type pollInviteesInput struct {
+	UserIDs []uuid.UUID `json:"user_ids" validate:"max=200,unique,dive,required"`
+	Emails  []string    `json:"emails" validate:"max=200,dive,email"`
+}
+
+func (app *Application) CreatePollHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	var input struct {
+		Title       string            `json:"title" validate:"required,max=200"`
+		Description string            `json:"description" validate:"max=2000"`
+		Options     []pollOptionInput `json:"options" validate:"required,min=1,max=50,dive"`
+		pollInviteesInput
+	}
+
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.validator.Struct(input); err != nil {
+		app.failedValidationResponse(w, r, err)
+		return
+	}
+
+	invitees, ok := app.resolvePollInvitees(w, r, input.pollInviteesInput)
+	if !ok {
+		return
+	}
+
+	poll := &models.Poll{
+		OrgID:       requester.OrgID,
+		OwnerID:     requester.UserID,
+		Title:       input.Title,
+		Description: input.Description,
+	}
+	for _, option := range input.Options {
+		poll.Options = append(poll.Options, models.PollOption{StartAt: option.StartAt.UTC(), EndAt: option.EndAt.UTC()})
+	}
+
+	invitations, err := app.models.Poll.Insert(poll, invitees)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.sendPollInvitations(poll, requester.Username, invitations)
+
+	if err := app.writeJSON(w, http.StatusCreated, map[string]any{"poll": poll}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) ListPollsHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	polls, err := app.models.Poll.GetForUser(requester.UserID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"polls": polls}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetPollHandler returns the poll with its tally to the owner and to invited
+// users.
+func (app *Application) GetPollHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	poll, ok := app.readPoll(w, r)
+	if !ok {
+		return
+	}
+
+	if poll.OwnerID != requester.UserID {
+		participant, err := app.models.Poll.IsParticipant(poll.ID, requester.UserID)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+		if !participant {
+			app.pollNotFoundResponse(w, r)
+			return
+		}
+	}
+
+	tally, err := app.models.Poll.Tally(poll.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"poll": poll, "tally": tally}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) AddPollParticipantsHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	poll, ok := app.readOwnPoll(w, r, requester)
+	if !ok {
+		return
+	}
+
+	var input pollInviteesInput
+
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.validator.Struct(input); err != nil {
+		app.failedValidationResponse(w, r, err)
+		return
+	}
+
+	invitees, ok := app.resolvePollInvitees(w, r, input)
+	if !ok {
+		return
+	}
+
+	invitations, err := app.models.Poll.AddParticipants(poll.ID, invitees)
+	if err != nil {
+		app.pollErrorResponse(w, r, err)
+		return
+	}
+
+	app.sendPollInvitations(poll, requester.Username, invitations)
+
+	participants := make([]models.PollParticipant, 0, len(invitations))
+	for _, invitation := range invitations {
+		participants = append(participants, invitation.Participant)
+	}
+
+	if err := app.writeJSON(w, http.StatusCreated, map[string]any{"participants": participants}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) ClosePollHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	poll, ok := app.readOwnPoll(w, r, requester)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		OptionID *uuid.UUID `json:"option_id"`
+	}
+
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.models.Poll.Close(poll.ID, input.OptionID); err != nil {
+		app.pollErrorResponse(w, r, err)
+		return
+	}
+
+	poll, err := app.models.Poll.GetByID(requester.OrgID, poll.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"poll": poll}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) DeletePollHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	poll, ok := app.readOwnPoll(w, r, requester)
+	if !ok {
+		return
+	}
+
+	if err := app.models.Poll.Delete(poll.ID); err != nil {
+		app.pollErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusNoContent, nil, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetPollInviteHandler is reached through a participant's link and needs no
+// login: the token identifies both the poll and the participant.
+func (app *Application) GetPollInviteHandler(w http.ResponseWriter, r *http.Request) {
+	poll, participant, ok := app.readPollInvite(w, r)
+	if !ok {
+		return
+	}
+
+	votes, err := app.models.Poll.GetVotes(participant.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	tally, err := app.models.Poll.Tally(poll.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := map[string]any{
+		"poll":        poll,
+		"participant": participant,
+		"votes":       votes,
+		"tally":       tally,
+	}
+	if err := app.writeJSON(w, http.StatusOK, response, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) VotePollInviteHandler(w http.ResponseWriter, r *http.Request) {
+	poll, participant, ok := app.readPollInvite(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Votes []struct {
+			OptionID uuid.UUID `json:"option_id" validate:"required"`
+			Choice   string    `json:"choice" validate:"required,oneof=yes maybe no"`
+		} `json:"votes" validate:"required,min=1,max=50,dive"`
+	}
+
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.validator.Struct(input); err != nil {
+		app.failedValidationResponse(w, r, err)
+		return
+	}
+
+	votes := make([]models.PollVote, 0, len(input.Votes))
+	for _, vote := range input.Votes {
+		votes = append(votes, models.PollVote{OptionID: vote.OptionID, Choice: models.PollChoice(vote.Choice)})
+	}
+
+	if err := app.models.Poll.SetVotes(poll.ID, participant.ID, votes); err != nil {
+		app.pollErrorResponse(w, r, err)
+		return
+	}
+
+	votes, err := app.models.Poll.GetVotes(participant.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	tally, err := app.models.Poll.Tally(poll.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"votes": votes, "tally": tally}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) readPoll(w http.ResponseWriter, r *http.Request) (*models.Poll, bool) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	pollID, err := app.readUUIDParam(r, "pollID", "poll id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return nil, false
+	}
+
+	poll, err := app.models.Poll.GetByID(requester.OrgID, pollID)
+	if err != nil {
+		app.pollErrorResponse(w, r, err)
+		return nil, false
+	}
+
+	return poll, true
+}
+
+// readOwnPoll loads the poll from the URL and hides it from everyone but its
+// owner.
+func (app *Application) readOwnPoll(w http.ResponseWriter, r *http.Request, requester *RequesterInfo) (*models.Poll, bool) {
+	poll, ok := app.readPoll(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	if poll.OwnerID != requester.UserID {
+		app.pollNotFoundResponse(w, r)
+		return nil, false
+	}
+
+	return poll, true
+}
+
+func (app *Application) readPollInvite(w http.ResponseWriter, r *http.Request) (*models.Poll, *models.PollParticipant, bool) {
+	token := chi.URLParam(r, "token")
+	if token == "" {
+		app.badRequestResponse(w, r, errors.New("token is required"))
+		return nil, nil, false
+	}
+
+	poll, participant, err := app.models.Poll.GetByToken(token)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.errorResponse(w, r, http.StatusNotFound, "POLL_INVITE_NOT_FOUND", "poll invitation not found", nil)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return nil, nil, false
+	}
+
+	return poll, participant, true
+}
+
+// resolvePollInvitees turns user IDs and email addresses into invitees.
+// Unknown user IDs, including those of other organizations, are rejected; an
+// address that belongs to an invited user is only invited once.
+func (app *Application) resolvePollInvitees(w http.ResponseWriter, r *http.Request, input pollInviteesInput) ([]models.PollInvitee, bool) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	invitees := []models.PollInvitee{}
+	seen := make(map[string]bool)
+
+	if len(input.UserIDs) > 0 {
+		users, err := app.models.User.GetManyByID(requester.OrgID, input.UserIDs)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return nil, false
+		}
+
+		if len(users) != len(input.UserIDs) {
+			found := make(map[uuid.UUID]bool, len(users))
+			for _, user := range users {
+				found[user.ID] = true
+			}
+
+			missing := []uuid.UUID{}
+			for _, userID := range input.UserIDs {
+				if !found[userID] {
+					missing = append(missing, userID)
+				}
+			}
+
+			app.errorResponse(w, r, http.StatusNotFound, "USER_NOT_FOUND", "one or more users could not be found", map[string]any{"user_ids": missing})
+			return nil, false
+		}
+
+		for _, user := range users {
+			seen[strings.ToLower(user.Email)] = true
+			invitees = append(invitees, models.PollInvitee{UserID: &user.ID, Email: user.Email, Name: user.Name})
+		}
+	}
+
+	for _, email := range input.Emails {
+		if seen[strings.ToLower(email)] {
+			continue
+		}
+		seen[strings.ToLower(email)] = true
+		invitees = append(invitees, models.PollInvitee{Email: email})
+	}
+
+	return invitees, true
+}
+
+func (app *Application) pollNotFoundResponse(w http.ResponseWriter, r *http.Request) {
+	app.errorResponse(w, r, http.StatusNotFound, "POLL_NOT_FOUND", "poll not found", nil)
+}
+
+func (app *Application) pollErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, models.ErrRecordNotFound):
+		app.pollNotFoundResponse(w, r)
+	case errors.Is(err, models.ErrPollClosed):
+		app.errorResponse(w, r, http.StatusConflict, "POLL_CLOSED", "the poll is closed", nil)
+	case errors.Is(err, models.ErrInvalidPollOption):
+		app.errorResponse(w, r, http.StatusUnprocessableEntity, "POLL_OPTION_INVALID", "one or more options do not belong to this poll", nil)
+	default:
+		app.internalServerError(w, r, err)
+	}
+}
+
+// sendPollInvitations emails every new participant their personal voting
+// link. Times are shown in the user's time zone, or UTC for external guests.
+func (app *Application) sendPollInvitations(poll *models.Poll, ownerName string, invitations []models.PollInvitation) {
+	if len(invitations) == 0 {
+		return
+	}
+
+	app.background(func() {
+		userIDs := []uuid.UUID{}
+		for _, invitation := range invitations {
+			if invitation.Participant.UserID != nil {
+				userIDs = append(userIDs, *invitation.Participant.UserID)
+			}
+		}
+
+		timeZones := make(map[uuid.UUID]string, len(userIDs))
+		if len(userIDs) > 0 {
+			users, err := app.models.User.GetManyByID(poll.OrgID, userIDs)
+			if err != nil {
+				app.logger.Error("failed to load users for poll invitations", "error", err)
+			}
+			for _, user := range users {
+				timeZones[user.ID] = user.TimeZone
+			}
+		}
+
+		type optionData struct {
+			Start string
+			End   string
+		}
+
+		for _, invitation := range invitations {
+			participant := invitation.Participant
+
+			timeZone := "UTC"
+			if participant.UserID != nil && timeZones[*participant.UserID] != "" {
+				timeZone = timeZones[*participant.UserID]
+			}
+			location, err := time.LoadLocation(timeZone)
+			if err != nil {
+				location, timeZone = time.UTC, "UTC"
+			}
+
+			options := make([]optionData, 0, len(poll.Options))
+			for _, option := range poll.Options {
+				options = append(options, optionData{
+					Start: option.StartAt.In(location).Format("Mon Jan 2 2006, 15:04"),
+					End:   option.EndAt.In(location).Format("Mon Jan 2 2006, 15:04"),
+				})
+			}
+
+			name := participant.Name
+			if name == "" {
+				name = participant.Email
+			}
+
+			data := map[string]any{
+				"name":        name,
+				"owner":       ownerName,
+				"title":       poll.Title,
+				"description": poll.Description,
+				"timeZone":    timeZone,
+				"options":     options,
+				"link":        strings.TrimRight(app.config.Frontend.URL, "/") + "/polls/respond/" + invitation.Token,
+			}
+
+			if err := app.mailer.SendHTML(participant.Email, "You're Invited: "+poll.Title, "poll_invitation.html", data); err != nil {
+				app.logger.Error("failed to send poll invitation email", "error", err, "email", participant.Email)
+				continue
+			}
+			app.logger.Info("poll invitation email sent", "email", participant.Email)
+		}
+	})
+}
@@ -0,0 +1,240 @@
+package application
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jonathanhu237/when-works/backend/internal/models"
+)
+
+func (app *Application) roleErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, models.ErrRecordNotFound):
+		app.errorResponse(w, r, http.StatusNotFound, "ROLE_NOT_FOUND", "role not found", nil)
+	case errors.Is(err, models.ErrRoleNameConflict):
+		app.errorResponse(w, r, http.StatusConflict, "ROLE_NAME_CONFLICT", "role name already exists", nil)
+	case errors.Is(err, models.ErrRoleBuiltIn):
+		app.errorResponse(w, r, http.StatusConflict, "ROLE_BUILT_IN", "built-in roles cannot be changed", nil)
+	case errors.Is(err, models.ErrUnknownPermission):
+		app.errorResponse(w, r, http.StatusUnprocessableEntity, "ROLE_PERMISSION_INVALID", "unknown permission", nil)
+	case errors.Is(err, models.ErrUnknownRole):
+		app.errorResponse(w, r, http.StatusUnprocessableEntity, "ROLE_INVALID", "unknown role", nil)
+	case errors.Is(err, models.ErrLastSuperadmin):
+		app.errorResponse(w, r, http.StatusConflict, "ROLE_LAST_SUPERADMIN", "at least one user must keep the superadmin role", nil)
+	default:
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) ListPermissionsHandler(w http.ResponseWriter, r *http.Request) {
+	permissions, err := app.models.Role.GetPermissions()
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"permissions": permissions}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) ListRolesHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	roles, err := app.models.Role.GetAll(requester.OrgID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"roles": roles}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) GetRoleHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	roleID, err := app.readUUIDParam(r, "roleID", "role id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	role, err := app.models.Role.GetByID(requester.OrgID, roleID)
+	if err != nil {
+		app.roleErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"role": role}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) CreateRoleHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	var input struct {
+		Name        string   `json:"name" validate:"required,max=50"`
+		Description string   `json:"description" validate:"max=200"`
+		Permissions []string `json:"permissions" validate:"required,unique"`
+	}
+
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.validator.Struct(input); err != nil {
+		app.failedValidationResponse(w, r, err)
+		return
+	}
+
+	role := &models.Role{
+		Name:        input.Name,
+		Description: input.Description,
+		Permissions: input.Permissions,
+	}
+
+	if err := app.models.Role.Insert(requester.OrgID, role); err != nil {
+		app.roleErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusCreated, map[string]any{"role": role}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) UpdateRoleHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	roleID, err := app.readUUIDParam(r, "roleID", "role id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var input struct {
+		Name        *string   `json:"name" validate:"omitempty,min=1,max=50"`
+		Description *string   `json:"description" validate:"omitempty,max=200"`
+		Permissions *[]string `json:"permissions" validate:"omitempty,unique"`
+	}
+
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Name == nil && input.Description == nil && input.Permissions == nil {
+		app.badRequestResponse(w, r, errors.New("at least one field must be provided"))
+		return
+	}
+
+	if err := app.validator.Struct(input); err != nil {
+		app.failedValidationResponse(w, r, err)
+		return
+	}
+
+	role, err := app.models.Role.GetByID(requester.OrgID, roleID)
+	if err != nil {
+		app.roleErrorResponse(w, r, err)
+		return
+	}
+
+	if input.Name != nil {
+		role.Name = *input.Name
+	}
+	if input.Description != nil {
+		role.Description = *input.Description
+	}
+	if input.Permissions != nil {
+		role.Permissions = *input.Permissions
+	}
+
+	if err := app.models.Role.Update(requester.OrgID, role); err != nil {
+		app.roleErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"role": role}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) DeleteRoleHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	roleID, err := app.readUUIDParam(r, "roleID", "role id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.models.Role.Delete(requester.OrgID, roleID); err != nil {
+		app.roleErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusNoContent, nil, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// SetUserRolesHandler replaces the roles of a user. The new permissions apply
+// from the user's next request.
+func (app *Application) SetUserRolesHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	userID, err := app.readUUIDParam(r, "userID", "user id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var input struct {
+		RoleIDs []uuid.UUID `json:"role_ids" validate:"required,unique"`
+	}
+
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.validator.Struct(input); err != nil {
+		app.failedValidationResponse(w, r, err)
+		return
+	}
+
+	if _, err := app.models.User.GetByID(userID); err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.errorResponse(w, r, http.StatusNotFound, "USER_NOT_FOUND", "user not found", nil)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.models.Role.SetUserRoles(requester.OrgID, userID, input.RoleIDs); err != nil {
+		app.roleErrorResponse(w, r, err)
+		return
+	}
+
+	user, err := app.models.User.GetByID(userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.errorResponse(w, r, http.StatusNotFound, "USER_NOT_FOUND", "user not found", nil)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"user": user}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/jonathanhu237/when-works/backend/internal/models"
 )
 
 func (app *Application) routes() http.Handler {
@@ -11,24 +12,210 @@ func (app *Application) routes() http.Handler {
 	router.NotFound(app.notFound)
 	router.MethodNotAllowed(app.methodNotAllowed)
 
+	authLimit := app.rateLimit("auth", app.config.RateLimit.Auth)
+	clientLimit := app.rateLimit("client", app.config.RateLimit.Client)
+	publicLimit := app.rateLimit("public", app.config.RateLimit.Public)
+	userLimit := app.rateLimit("user", app.config.RateLimit.User)
+	adminLimit := app.rateLimit("admin", app.config.RateLimit.Admin)
+
+	writeScope := app.requireScope(models.APIScopeWrite)
+	scheduleAdminScope := app.requireScope(models.APIScopeScheduleAdmin, models.APIScopeAdmin)
+	adminScope := app.requireScope(models.APIScopeAdmin)
+
 	router.Get("/v1/healthcheck", app.healthcheckHandler)
-	router.Route("/v1/auth", func(r chi.Router) {
+	router.With(authLimit).Route("/v1/auth", func(r chi.Router) {
 		r.Post("/login", app.LoginHandler)
+		r.Post("/refresh", app.RefreshHandler)
+		r.Post("/forgot-password", app.ForgotPasswordHandler)
+		r.Post("/reset-password", app.ResetPasswordHandler)
+		r.Post("/activate", app.ActivateAccountHandler)
+		r.Post("/login/totp", app.LoginTOTPHandler)
 		r.Post("/logout", app.LogoutHandler)
 	})
-	router.With(app.requireAuth).Route("/v1/me", func(r chi.Router) {
+	router.With(clientLimit, app.requireAuth, writeScope, userLimit).Route("/v1/me", func(r chi.Router) {
 		r.Get("/", app.GetMeHandler)
 		r.Patch("/", app.UpdateMeHandler)
-		r.Post("/update-password", app.UpdateMePasswordHandler)
+		r.With(app.requireSession).Post("/update-password", app.UpdateMePasswordHandler)
+		r.With(app.requireSession).Post("/calendar-token", app.RotateMyCalendarTokenHandler)
+		r.With(app.requireSession).Delete("/calendar-token", app.RevokeMyCalendarTokenHandler)
+		r.With(app.requireSession).Route("/tokens", func(r chi.Router) {
+			r.Get("/", app.ListMyAPITokensHandler)
+			r.Post("/", app.CreateMyAPITokenHandler)
+			r.Delete("/{tokenID}", app.RevokeMyAPITokenHandler)
+		})
+		r.With(app.requireSession).Route("/totp", func(r chi.Router) {
+			r.Get("/", app.GetMyTOTPHandler)
+			r.Post("/", app.BeginMyTOTPHandler)
+			r.Delete("/", app.DisableMyTOTPHandler)
+			r.Post("/confirm", app.ConfirmMyTOTPHandler)
+			r.Post("/recovery-codes", app.RegenerateMyRecoveryCodesHandler)
+		})
+		r.With(app.requireSession).Route("/sessions", func(r chi.Router) {
+			r.Get("/", app.ListMySessionsHandler)
+			r.Delete("/{sessionID}", app.RevokeMySessionHandler)
+		})
+		r.Route("/availability", func(r chi.Router) {
+			r.Get("/", app.GetMyAvailabilityHandler)
+			r.Put("/", app.ReplaceMyAvailabilityHandler)
+			r.Get("/resolved", app.GetMyResolvedAvailabilityHandler)
+			r.Route("/busy-blocks", func(r chi.Router) {
+				r.Get("/", app.ListMyBusyBlocksHandler)
+				r.Put("/", app.ImportMyBusyBlocksHandler)
+				r.Delete("/", app.DeleteMyBusyBlocksHandler)
+			})
+			r.Route("/exceptions", func(r chi.Router) {
+				r.Get("/", app.ListMyAvailabilityExceptionsHandler)
+				r.Post("/", app.CreateMyAvailabilityExceptionHandler)
+				r.Route("/{exceptionID}", func(r chi.Router) {
+					r.Get("/", app.GetMyAvailabilityExceptionHandler)
+					r.Patch("/", app.UpdateMyAvailabilityExceptionHandler)
+					r.Delete("/", app.DeleteMyAvailabilityExceptionHandler)
+				})
+			})
+		})
+		r.Get("/shifts", app.ListMyShiftsHandler)
+		r.Get("/teams", app.ListMyTeamsHandler)
+		r.Get("/organization", app.GetMyOrganizationHandler)
+		r.Route("/swaps", func(r chi.Router) {
+			r.Get("/", app.ListMySwapsHandler)
+			r.Post("/", app.CreateMySwapHandler)
+			r.Post("/{swapID}/cancel", app.CancelMySwapHandler)
+		})
+		r.Route("/time-off", func(r chi.Router) {
+			r.Get("/", app.ListMyTimeOffHandler)
+			r.Post("/", app.CreateMyTimeOffHandler)
+			r.Post("/{timeOffID}/cancel", app.CancelMyTimeOffHandler)
+		})
+	})
+	router.With(clientLimit, app.requireAuth, writeScope, userLimit).Route("/v1/availability", func(r chi.Router) {
+		r.Post("/slots", app.FindSlotsHandler)
+	})
+	router.With(clientLimit, app.requireAuth, writeScope, userLimit).Route("/v1/polls", func(r chi.Router) {
+		r.Get("/", app.ListPollsHandler)
+		r.Post("/", app.CreatePollHandler)
+		r.Route("/{pollID}", func(r chi.Router) {
+			r.Get("/", app.GetPollHandler)
+			r.Delete("/", app.DeletePollHandler)
+			r.Post("/participants", app.AddPollParticipantsHandler)
+			r.Post("/close", app.ClosePollHandler)
+		})
+	})
+	router.With(publicLimit).Route("/v1/poll-invites/{token}", func(r chi.Router) {
+		r.Get("/", app.GetPollInviteHandler)
+		r.Put("/votes", app.VotePollInviteHandler)
+	})
+	router.With(publicLimit).Get("/v1/calendar/{token}.ics", app.CalendarFeedHandler)
+	router.With(clientLimit, app.requireAuth, userLimit).Route("/v1/swaps", func(r chi.Router) {
+		r.With(writeScope).Get("/", app.ListOpenSwapsHandler)
+		r.With(app.requirePermission(models.PermSwapsRead), scheduleAdminScope).Get("/pending", app.ListPendingSwapsHandler)
+		r.Route("/{swapID}", func(r chi.Router) {
+			r.With(writeScope).Get("/", app.GetSwapHandler)
+			r.With(writeScope).Post("/accept", app.AcceptSwapHandler)
+			r.With(app.requirePermission(models.PermSwapsManage), scheduleAdminScope).Post("/approve", app.ApproveSwapHandler)
+			r.With(app.requirePermission(models.PermSwapsManage), scheduleAdminScope).Post("/reject", app.RejectSwapHandler)
+		})
+	})
+	router.With(clientLimit, app.requireAuth, app.requirePermission(models.PermTimeOffRead), scheduleAdminScope, adminLimit).Route("/v1/time-off", func(r chi.Router) {
+		manage := app.requirePermission(models.PermTimeOffManage)
+
+		r.Get("/", app.ListTimeOffHandler)
+		r.Route("/{timeOffID}", func(r chi.Router) {
+			r.Get("/", app.GetTimeOffHandler)
+			r.With(manage).Post("/approve", app.ApproveTimeOffHandler)
+			r.With(manage).Post("/deny", app.DenyTimeOffHandler)
+		})
 	})
-	router.With(app.requireAuth, app.requireAdmin).Route("/v1/users", func(r chi.Router) {
+	router.With(clientLimit, app.requireAuth, app.requirePermissionOrManager(models.PermUsersRead), adminScope, adminLimit).Route("/v1/users", func(r chi.Router) {
+		manage := app.requirePermission(models.PermUsersManage)
+
 		r.Get("/", app.ListUsersHandler)
-		r.Post("/", app.CreateUserHandler)
+		r.With(manage).Post("/", app.CreateUserHandler)
 		r.Route("/{userID}", func(r chi.Router) {
+			r.Use(app.requireUserInScope(models.PermUsersRead))
+
 			r.Get("/", app.GetUserHandler)
-			r.Patch("/", app.UpdateUserHandler)
-			r.Delete("/", app.DeleteUserHandler)
-			r.Post("/reset-password", app.ResetUserPasswordHandler)
+			r.Get("/availability", app.GetUserAvailabilityHandler)
+			r.With(manage).Patch("/", app.UpdateUserHandler)
+			r.With(manage).Delete("/", app.DeleteUserHandler)
+			r.With(manage).Post("/deactivate", app.DeactivateUserHandler)
+			r.With(manage).Post("/restore", app.RestoreUserHandler)
+			r.With(app.requirePermission(models.PermUsersPurge)).Delete("/purge", app.PurgeUserHandler)
+			r.With(manage).Post("/reset-password", app.ResetUserPasswordHandler)
+			r.With(manage).Delete("/totp", app.DisableUserTOTPHandler)
+			r.With(manage).Post("/unlock", app.UnlockUserHandler)
+			r.With(app.requirePermission(models.PermRolesManage)).Put("/roles", app.SetUserRolesHandler)
+			r.With(manage).Route("/invitation", func(r chi.Router) {
+				r.Post("/resend", app.ResendInvitationHandler)
+				r.Delete("/", app.RevokeInvitationHandler)
+			})
+			r.Route("/sessions", func(r chi.Router) {
+				r.Get("/", app.ListUserSessionsHandler)
+				r.With(manage).Delete("/", app.RevokeUserSessionsHandler)
+				r.With(manage).Delete("/{sessionID}", app.RevokeUserSessionHandler)
+			})
+		})
+	})
+	router.With(clientLimit, app.requireAuth, app.requirePermissionOrManager(models.PermUsersRead), adminScope, adminLimit).Route("/v1/teams", func(r chi.Router) {
+		manage := app.requirePermission(models.PermTeamsManage)
+
+		r.Get("/", app.ListTeamsHandler)
+		r.With(manage).Post("/", app.CreateTeamHandler)
+		r.Route("/{teamID}", func(r chi.Router) {
+			r.Get("/", app.GetTeamHandler)
+			r.With(manage).Patch("/", app.UpdateTeamHandler)
+			r.With(manage).Delete("/", app.DeleteTeamHandler)
+			r.Get("/members", app.ListTeamMembersHandler)
+			r.With(manage).Put("/members/{userID}", app.SetTeamMemberHandler)
+			r.With(manage).Delete("/members/{userID}", app.RemoveTeamMemberHandler)
+		})
+	})
+	router.With(clientLimit, app.requireAuth, app.requirePermission(models.PermOrganizationsManage), app.requireHostOrganization, adminScope, adminLimit).Route("/v1/organizations", func(r chi.Router) {
+		r.Get("/", app.ListOrganizationsHandler)
+		r.Post("/", app.CreateOrganizationHandler)
+	})
+	router.With(clientLimit, app.requireAuth, app.requirePermission(models.PermUsersRead), adminScope, adminLimit).Route("/v1/roles", func(r chi.Router) {
+		manage := app.requirePermission(models.PermRolesManage)
+
+		r.Get("/", app.ListRolesHandler)
+		r.With(manage).Post("/", app.CreateRoleHandler)
+		r.Get("/permissions", app.ListPermissionsHandler)
+		r.Route("/{roleID}", func(r chi.Router) {
+			r.Get("/", app.GetRoleHandler)
+			r.With(manage).Patch("/", app.UpdateRoleHandler)
+			r.With(manage).Delete("/", app.DeleteRoleHandler)
+		})
+	})
+	router.With(clientLimit, app.requireAuth, app.requirePermissionOrManager(models.PermShiftsRead), scheduleAdminScope, adminLimit).Route("/v1/shifts", func(r chi.Router) {
+		manage := app.requirePermission(models.PermShiftsManage)
+		assign := app.requirePermissionOrManager(models.PermShiftsManage)
+
+		r.Get("/", app.ListShiftsHandler)
+		r.With(manage).Post("/", app.CreateShiftHandler)
+		r.Route("/{shiftID}", func(r chi.Router) {
+			r.Get("/", app.GetShiftHandler)
+			r.With(manage).Patch("/", app.UpdateShiftHandler)
+			r.With(manage).Delete("/", app.DeleteShiftHandler)
+			r.With(assign).Post("/assignments", app.CreateShiftAssignmentHandler)
+			r.With(assign, app.requireUserInScope(models.PermShiftsManage)).Delete("/assignments/{userID}", app.DeleteShiftAssignmentHandler)
+		})
+	})
+	router.With(clientLimit, app.requireAuth, app.requirePermissionOrManager(models.PermShiftsManage), scheduleAdminScope, adminLimit).Route("/v1/scheduler", func(r chi.Router) {
+		r.Post("/preview", app.PreviewScheduleHandler)
+		r.Post("/commit", app.CommitScheduleHandler)
+	})
+	router.With(clientLimit, app.requireAuth, app.requirePermission(models.PermShiftsRead), scheduleAdminScope, adminLimit).Route("/v1/schedules", func(r chi.Router) {
+		manage := app.requirePermission(models.PermShiftsManage)
+
+		r.Get("/", app.ListSchedulesHandler)
+		r.With(manage).Post("/", app.CreateScheduleHandler)
+		r.Route("/{scheduleID}", func(r chi.Router) {
+			r.Get("/", app.GetScheduleHandler)
+			r.With(manage).Post("/publish", app.PublishScheduleHandler)
+			r.With(manage).Post("/revert", app.RevertScheduleHandler)
+			r.With(manage).Post("/archive", app.ArchiveScheduleHandler)
+			r.Get("/diff", app.DiffScheduleVersionsHandler)
+			r.Get("/versions", app.ListScheduleVersionsHandler)
+			r.Get("/versions/{version}", app.GetScheduleVersionHandler)
 		})
 	})
 
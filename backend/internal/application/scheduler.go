@@ -0,0 +1,205 @@
+package application
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jonathanhu237/when-works/backend/internal/models"
+	"github.com/jonathanhu237/when-works/backend/internal/scheduler"
+)
+
+// bookingLookaround is how far outside the scheduled range existing shifts
+// are loaded, so rest periods and weekly hours see neighbouring bookings.
+const bookingLookaround = 7 * 24 * time.Hour
+
+type scheduleInput struct {
+	From            time.Time   `json:"from" validate:"required"`
+	To              time.Time   `json:"to" validate:"required,gtfield=From"`
+	UserIDs         []uuid.UUID `json:"user_ids" validate:"omitempty,max=500,unique,dive,required"`
+	MaxHoursPerWeek float64     `json:"max_hours_per_week" validate:"min=0,max=168"`
+	MinRestHours    float64     `json:"min_rest_hours" validate:"min=0,max=72"`
+}
+
+func (app *Application) PreviewScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	var input scheduleInput
+
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.validator.Struct(input); err != nil {
+		app.failedValidationResponse(w, r, err)
+		return
+	}
+
+	result, ok := app.generateSchedule(w, r, input)
+	if !ok {
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"schedule": result}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) CommitScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	var input struct {
+		scheduleInput
+		// Assignments, when given, must match what the generator produces now.
+		// This guards against committing something other than the preview.
+		Assignments []scheduler.Assignment `json:"assignments" validate:"omitempty,dive"`
+	}
+
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.validator.Struct(input); err != nil {
+		app.failedValidationResponse(w, r, err)
+		return
+	}
+
+	result, ok := app.generateSchedule(w, r, input.scheduleInput)
+	if !ok {
+		return
+	}
+
+	if input.Assignments != nil && !slices.Equal(input.Assignments, result.Assignments) {
+		app.errorResponse(w, r, http.StatusConflict, "SCHEDULE_PREVIEW_STALE", "the data changed since the preview was generated", map[string]any{"schedule": result})
+		return
+	}
+
+	assignments := make([]models.ShiftAssignment, len(result.Assignments))
+	for i, assignment := range result.Assignments {
+		assignments[i] = models.ShiftAssignment{ShiftID: assignment.ShiftID, UserID: assignment.UserID}
+	}
+
+	if err := app.models.Shift.AssignMany(requester.OrgID, assignments); err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.errorResponse(w, r, http.StatusConflict, "SCHEDULE_PREVIEW_STALE", "the data changed since the preview was generated", nil)
+		default:
+			app.assignmentErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	response := map[string]any{
+		"assignments": assignments,
+		"unfilled":    result.Unfilled,
+	}
+	if err := app.writeJSON(w, http.StatusCreated, response, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// generateSchedule loads everything the solver needs and runs it. When it
+// returns false an error response has already been written.
+func (app *Application) generateSchedule(w http.ResponseWriter, r *http.Request, input scheduleInput) (scheduler.Result, bool) {
+	from, to := input.From.UTC(), input.To.UTC()
+	if to.Sub(from) > maxResolveSpan {
+		app.badRequestResponse(w, r, errors.New("range must not exceed 93 days"))
+		return scheduler.Result{}, false
+	}
+
+	// Candidates default to every user the requester may schedule; team
+	// managers only schedule their own members
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+	scope := requester.Scope(models.PermShiftsManage)
+
+	if len(input.UserIDs) > 0 {
+		count, err := app.models.User.CountInScope(input.UserIDs, scope)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return scheduler.Result{}, false
+		}
+		if count != len(input.UserIDs) {
+			app.errorResponse(w, r, http.StatusNotFound, "USER_NOT_FOUND", "one or more users could not be found", nil)
+			return scheduler.Result{}, false
+		}
+	}
+
+	var users []models.User
+	var err error
+	if len(input.UserIDs) == 0 {
+		users, err = app.models.User.GetAll(scope)
+	} else {
+		users, err = app.models.User.GetManyByID(requester.OrgID, input.UserIDs)
+	}
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return scheduler.Result{}, false
+	}
+
+	// Deactivated users cannot be assigned, so they are never proposed
+	users = slices.DeleteFunc(users, func(user models.User) bool {
+		return user.Status != models.UserStatusActive && user.Status != models.UserStatusInvited
+	})
+
+	userIDs := make([]uuid.UUID, len(users))
+	for i, user := range users {
+		userIDs[i] = user.ID
+	}
+
+	availability, err := app.models.Availability.ResolveMany(userIDs, from, to)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return scheduler.Result{}, false
+	}
+
+	shifts, err := app.models.Shift.GetAll(models.AllUsers(requester.OrgID).WithArchived(), from.Add(-bookingLookaround), to.Add(bookingLookaround))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return scheduler.Result{}, false
+	}
+
+	// Only shifts fully inside the range are filled; the rest are bookings
+	bookings := make(map[uuid.UUID][]models.Interval)
+	var targets []scheduler.Shift
+	for _, shift := range shifts {
+		target := scheduler.Shift{
+			ID:                shift.ID,
+			StartAt:           shift.StartAt,
+			EndAt:             shift.EndAt,
+			RequiredHeadcount: shift.RequiredHeadcount,
+		}
+
+		for _, assignment := range shift.Assignments {
+			bookings[assignment.UserID] = append(bookings[assignment.UserID], models.Interval{Start: shift.StartAt, End: shift.EndAt})
+			target.AssignedUserIDs = append(target.AssignedUserIDs, assignment.UserID)
+		}
+
+		if !shift.StartAt.Before(from) && !shift.EndAt.After(to) {
+			targets = append(targets, target)
+		}
+	}
+
+	candidates := make([]scheduler.Candidate, 0, len(users))
+	for _, user := range users {
+		location, err := time.LoadLocation(user.TimeZone)
+		if err != nil {
+			location = time.UTC
+		}
+
+		candidates = append(candidates, scheduler.Candidate{
+			UserID:       user.ID,
+			Location:     location,
+			Availability: availability[user.ID],
+			Bookings:     bookings[user.ID],
+		})
+	}
+
+	limits := scheduler.Limits{
+		MaxHoursPerWeek: input.MaxHoursPerWeek,
+		MinRest:         time.Duration(input.MinRestHours * float64(time.Hour)),
+	}
+
+	return scheduler.Generate(targets, candidates, limits), true
+}
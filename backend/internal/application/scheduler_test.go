@@ -0,0 +1,56 @@
+package application
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jonathanhu237/when-works/backend/internal/models"
+)
+
+func TestSchedulerSkipsDeactivatedUsers(t *testing.T) {
+	app := newTestApplication(t)
+	org, _ := createOrganization(t, app, "Acme", "owner")
+	active := createUser(t, app, org.ID, "active")
+	deactivated := createUser(t, app, org.ID, "deactivated")
+	makeAvailable(t, app, active.ID)
+	makeAvailable(t, app, deactivated.ID)
+	if err := app.models.User.Deactivate(org.ID, deactivated.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2025, time.March, 3, 9, 0, 0, 0, time.UTC)
+	shift := &models.Shift{OrgID: org.ID, Title: "Morning", StartAt: start, EndAt: start.Add(8 * time.Hour), RequiredHeadcount: 2}
+	if err := app.models.Shift.Insert(shift); err != nil {
+		t.Fatal(err)
+	}
+
+	cookies := login(t, app, "owner")
+	input := map[string]any{"from": start.Add(-time.Hour), "to": start.Add(24 * time.Hour)}
+
+	res := serve(app, http.MethodPost, "/v1/scheduler/commit", jsonBody(t, input), cookies)
+	if res.StatusCode != http.StatusCreated {
+		t.Fatalf("commit: status %d, want %d", res.StatusCode, http.StatusCreated)
+	}
+
+	var body struct {
+		Assignments []models.ShiftAssignment `json:"assignments"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Assignments) != 1 || body.Assignments[0].UserID != active.ID {
+		t.Errorf("assignments = %+v, want only %s", body.Assignments, active.ID)
+	}
+
+	stored, err := app.models.Shift.GetByID(org.ID, shift.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, assignment := range stored.Assignments {
+		if assignment.UserID == deactivated.ID {
+			t.Error("the deactivated user was assigned")
+		}
+	}
+}
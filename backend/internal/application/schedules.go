@@ -0,0 +1,355 @@
+package application
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jonathanhu237/when-works/backend/internal/models"
+)
+
+func (app *Application) CreateScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	var input struct {
+		Title       string    `json:"title" validate:"required,max=200"`
+		PeriodStart time.Time `json:"period_start" validate:"required"`
+		PeriodEnd   time.Time `json:"period_end" validate:"required,gtfield=PeriodStart"`
+	}
+
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.validator.Struct(input); err != nil {
+		app.failedValidationResponse(w, r, err)
+		return
+	}
+
+	schedule := &models.Schedule{
+		OrgID:       requester.OrgID,
+		Title:       input.Title,
+		PeriodStart: input.PeriodStart,
+		PeriodEnd:   input.PeriodEnd,
+	}
+
+	if err := app.models.Schedule.Insert(schedule); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusCreated, map[string]any{"schedule": schedule}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) ListSchedulesHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	schedules, err := app.models.Schedule.GetAll(requester.OrgID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"schedules": schedules}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) GetScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	scheduleID, err := app.readUUIDParam(r, "scheduleID", "schedule id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	schedule, err := app.models.Schedule.GetByID(requester.OrgID, scheduleID)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.errorResponse(w, r, http.StatusNotFound, "SCHEDULE_NOT_FOUND", "schedule not found", nil)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"schedule": schedule}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) ListScheduleVersionsHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	scheduleID, err := app.readUUIDParam(r, "scheduleID", "schedule id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	versions, err := app.models.Schedule.GetVersions(requester.OrgID, scheduleID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"versions": versions}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) GetScheduleVersionHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	scheduleID, err := app.readUUIDParam(r, "scheduleID", "schedule id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	version, err := app.readIntParam(r, "version", "version")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	scheduleVersion, err := app.models.Schedule.GetVersion(requester.OrgID, scheduleID, version)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.errorResponse(w, r, http.StatusNotFound, "SCHEDULE_VERSION_NOT_FOUND", "schedule version not found", nil)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"version": scheduleVersion}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) DiffScheduleVersionsHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	scheduleID, err := app.readUUIDParam(r, "scheduleID", "schedule id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	qs := r.URL.Query()
+	from, err := strconv.Atoi(qs.Get("from"))
+	if err != nil || from < 1 {
+		app.badRequestResponse(w, r, errors.New("from must be a version number"))
+		return
+	}
+	to, err := strconv.Atoi(qs.Get("to"))
+	if err != nil || to < 1 {
+		app.badRequestResponse(w, r, errors.New("to must be a version number"))
+		return
+	}
+
+	versions := make([]*models.ScheduleVersion, 2)
+	for i, number := range []int{from, to} {
+		versions[i], err = app.models.Schedule.GetVersion(requester.OrgID, scheduleID, number)
+		if err != nil {
+			switch {
+			case errors.Is(err, models.ErrRecordNotFound):
+				app.errorResponse(w, r, http.StatusNotFound, "SCHEDULE_VERSION_NOT_FOUND", "schedule version not found", map[string]any{"version": number})
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+	}
+
+	response := map[string]any{
+		"from": from,
+		"to":   to,
+		"diff": models.DiffScheduleEntries(versions[0].Entries, versions[1].Entries),
+	}
+	if err := app.writeJSON(w, http.StatusOK, response, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) PublishScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	scheduleID, err := app.readUUIDParam(r, "scheduleID", "schedule id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	version, previous, err := app.models.Schedule.Publish(requester.OrgID, scheduleID, requester.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.errorResponse(w, r, http.StatusNotFound, "SCHEDULE_NOT_FOUND", "schedule not found", nil)
+		case errors.Is(err, models.ErrScheduleArchived):
+			app.errorResponse(w, r, http.StatusConflict, "SCHEDULE_ARCHIVED", "archived schedules cannot be changed", nil)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	var before []models.ScheduleEntry
+	if previous != nil {
+		before = previous.Entries
+	}
+	diff := models.DiffScheduleEntries(before, version.Entries)
+
+	schedule, err := app.models.Schedule.GetByID(requester.OrgID, scheduleID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.notifySchedulePublished(schedule, version, diff.AffectedUserIDs())
+
+	response := map[string]any{
+		"schedule": schedule,
+		"version":  version,
+		"diff":     diff,
+	}
+	if err := app.writeJSON(w, http.StatusOK, response, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) RevertScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	scheduleID, err := app.readUUIDParam(r, "scheduleID", "schedule id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var input struct {
+		Version int `json:"version" validate:"required,min=1"`
+	}
+
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.validator.Struct(input); err != nil {
+		app.failedValidationResponse(w, r, err)
+		return
+	}
+
+	conflicts, err := app.models.Schedule.Revert(requester.OrgID, scheduleID, input.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.errorResponse(w, r, http.StatusNotFound, "SCHEDULE_VERSION_NOT_FOUND", "schedule or version not found", nil)
+		case errors.Is(err, models.ErrScheduleArchived):
+			app.errorResponse(w, r, http.StatusConflict, "SCHEDULE_ARCHIVED", "archived schedules cannot be changed", nil)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	schedule, err := app.models.Schedule.GetByID(requester.OrgID, scheduleID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	// Entries that no longer fit were left out, each with the rule it broke
+	response := map[string]any{
+		"schedule":  schedule,
+		"conflicts": assignmentConflictDetails(conflicts),
+	}
+	if err := app.writeJSON(w, http.StatusOK, response, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) ArchiveScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	scheduleID, err := app.readUUIDParam(r, "scheduleID", "schedule id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.models.Schedule.Archive(requester.OrgID, scheduleID); err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.errorResponse(w, r, http.StatusNotFound, "SCHEDULE_NOT_FOUND", "schedule not found", nil)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusNoContent, nil, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// notifySchedulePublished emails every affected user their shifts in the new
+// version, formatted in their own time zone.
+func (app *Application) notifySchedulePublished(schedule *models.Schedule, version *models.ScheduleVersion, userIDs []uuid.UUID) {
+	if len(userIDs) == 0 {
+		return
+	}
+
+	app.background(func() {
+		users, err := app.models.User.GetManyByID(schedule.OrgID, userIDs)
+		if err != nil {
+			app.logger.Error("failed to load users for schedule notification", "error", err)
+			return
+		}
+
+		for _, user := range users {
+			location, err := time.LoadLocation(user.TimeZone)
+			if err != nil {
+				location = time.UTC
+			}
+
+			type shiftLine struct {
+				Title, Start, End string
+			}
+			var shifts []shiftLine
+			for _, entry := range version.Entries {
+				if entry.UserID == user.ID {
+					shifts = append(shifts, shiftLine{
+						Title: entry.Title,
+						Start: entry.StartAt.In(location).Format("Mon Jan 2, 15:04"),
+						End:   entry.EndAt.In(location).Format("Mon Jan 2, 15:04"),
+					})
+				}
+			}
+
+			data := map[string]any{
+				"name":     user.Name,
+				"title":    schedule.Title,
+				"version":  version.Version,
+				"timeZone": location.String(),
+				"shifts":   shifts,
+			}
+
+			if err := app.mailer.SendHTML(user.Email, "Your WhenWorks Schedule Was Published", "schedule_published.html", data); err != nil {
+				app.logger.Error("failed to send schedule published email", "error", err, "email", user.Email)
+				continue
+			}
+			app.logger.Info("schedule published email sent", "email", user.Email)
+		}
+	})
+}
@@ -0,0 +1,127 @@
+package application
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jonathanhu237/when-works/backend/internal/models"
+)
+
+func (app *Application) ListMySessionsHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	app.writeSessions(w, r, requester.UserID, requester.SessionID)
+}
+
+// RevokeMySessionHandler signs out one of the requester's devices. Revoking
+// the current session works too and amounts to logging out.
+func (app *Application) RevokeMySessionHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	sessionID, err := app.readUUIDParam(r, "sessionID", "session id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	app.revokeSession(w, r, sessionID, requester.UserID)
+}
+
+func (app *Application) ListUserSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	userID, err := app.readUUIDParam(r, "userID", "user id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if _, err := app.models.User.GetByID(userID); err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.errorResponse(w, r, http.StatusNotFound, "USER_NOT_FOUND", "user not found", nil)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.writeSessions(w, r, userID, requester.SessionID)
+}
+
+func (app *Application) RevokeUserSessionHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := app.readUUIDParam(r, "userID", "user id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	sessionID, err := app.readUUIDParam(r, "sessionID", "session id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	app.revokeSession(w, r, sessionID, userID)
+}
+
+// RevokeUserSessionsHandler signs the user out everywhere.
+func (app *Application) RevokeUserSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := app.readUUIDParam(r, "userID", "user id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if _, err := app.models.User.GetByID(userID); err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.errorResponse(w, r, http.StatusNotFound, "USER_NOT_FOUND", "user not found", nil)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.models.Session.RevokeAllForUser(userID, uuid.Nil); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusNoContent, nil, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) writeSessions(w http.ResponseWriter, r *http.Request, userID, currentSessionID uuid.UUID) {
+	sessions, err := app.models.Session.GetActiveForUser(userID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentSessionID
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"sessions": sessions}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) revokeSession(w http.ResponseWriter, r *http.Request, sessionID, userID uuid.UUID) {
+	if err := app.models.Session.RevokeForUser(sessionID, userID); err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.errorResponse(w, r, http.StatusNotFound, "SESSION_NOT_FOUND", "session not found", nil)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusNoContent, nil, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
@@ -0,0 +1,337 @@
+package application
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jonathanhu237/when-works/backend/internal/models"
+)
+
+type shiftInput struct {
+	Title             string    `json:"title" validate:"required,max=200"`
+	StartAt           time.Time `json:"start_at" validate:"required"`
+	EndAt             time.Time `json:"end_at" validate:"required,gtfield=StartAt"`
+	RequiredHeadcount int       `json:"required_headcount" validate:"required,min=1,max=1000"`
+	Tag               *string   `json:"tag" validate:"omitempty,min=1,max=100"`
+}
+
+func (app *Application) CreateShiftHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	var input shiftInput
+
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.validator.Struct(input); err != nil {
+		app.failedValidationResponse(w, r, err)
+		return
+	}
+
+	shift := &models.Shift{
+		OrgID:             requester.OrgID,
+		Title:             input.Title,
+		StartAt:           input.StartAt,
+		EndAt:             input.EndAt,
+		RequiredHeadcount: input.RequiredHeadcount,
+		Tag:               input.Tag,
+	}
+
+	if err := app.models.Shift.Insert(shift); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusCreated, map[string]any{"shift": shift}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) ListShiftsHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	from, to, err := app.readTimeRange(r.URL.Query(), maxResolveSpan)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	// Team managers only see the assignments of their own members
+	shifts, err := app.models.Shift.GetAll(requester.Scope(models.PermShiftsRead).WithArchived(), from, to)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"shifts": shifts}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) GetShiftHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	shiftID, err := app.readUUIDParam(r, "shiftID", "shift id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	shift, err := app.models.Shift.GetInScope(requester.Scope(models.PermShiftsRead).WithArchived(), shiftID)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.errorResponse(w, r, http.StatusNotFound, "SHIFT_NOT_FOUND", "shift not found", nil)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"shift": shift}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) UpdateShiftHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	shiftID, err := app.readUUIDParam(r, "shiftID", "shift id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var input struct {
+		Title             *string    `json:"title"`
+		StartAt           *time.Time `json:"start_at"`
+		EndAt             *time.Time `json:"end_at"`
+		RequiredHeadcount *int       `json:"required_headcount"`
+		Tag               *string    `json:"tag"`
+	}
+
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Title == nil && input.StartAt == nil && input.EndAt == nil && input.RequiredHeadcount == nil && input.Tag == nil {
+		app.badRequestResponse(w, r, errors.New("at least one field must be provided"))
+		return
+	}
+
+	shift, err := app.models.Shift.GetByID(requester.OrgID, shiftID)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.errorResponse(w, r, http.StatusNotFound, "SHIFT_NOT_FOUND", "shift not found", nil)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	// Validate the merged result so that partial updates cannot invert the range
+	merged := shiftInput{
+		Title:             shift.Title,
+		StartAt:           shift.StartAt,
+		EndAt:             shift.EndAt,
+		RequiredHeadcount: shift.RequiredHeadcount,
+		Tag:               shift.Tag,
+	}
+	if input.Title != nil {
+		merged.Title = *input.Title
+	}
+	if input.StartAt != nil {
+		merged.StartAt = *input.StartAt
+	}
+	if input.EndAt != nil {
+		merged.EndAt = *input.EndAt
+	}
+	if input.RequiredHeadcount != nil {
+		merged.RequiredHeadcount = *input.RequiredHeadcount
+	}
+	if input.Tag != nil {
+		// An empty tag clears it
+		merged.Tag = input.Tag
+		if *input.Tag == "" {
+			merged.Tag = nil
+		}
+	}
+
+	if err := app.validator.Struct(merged); err != nil {
+		app.failedValidationResponse(w, r, err)
+		return
+	}
+
+	shift.Title = merged.Title
+	shift.StartAt = merged.StartAt
+	shift.EndAt = merged.EndAt
+	shift.RequiredHeadcount = merged.RequiredHeadcount
+	shift.Tag = merged.Tag
+
+	if err := app.models.Shift.Update(shift); err != nil {
+		var conflictErr *models.AssignmentConflictError
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.errorResponse(w, r, http.StatusNotFound, "SHIFT_NOT_FOUND", "shift not found", nil)
+		case errors.As(err, &conflictErr):
+			app.assignmentConflictResponse(w, r, conflictErr.Conflicts)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"shift": shift}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) DeleteShiftHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	shiftID, err := app.readUUIDParam(r, "shiftID", "shift id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.models.Shift.Delete(requester.OrgID, shiftID); err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.errorResponse(w, r, http.StatusNotFound, "SHIFT_NOT_FOUND", "shift not found", nil)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusNoContent, nil, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) CreateShiftAssignmentHandler(w http.ResponseWriter, r *http.Request) {
+	shiftID, err := app.readUUIDParam(r, "shiftID", "shift id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var input struct {
+		UserID uuid.UUID `json:"user_id" validate:"required"`
+	}
+
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.validator.Struct(input); err != nil {
+		app.failedValidationResponse(w, r, err)
+		return
+	}
+
+	// Team managers may only assign their own members
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+	ok, err := app.models.User.InScope(input.UserID, requester.Scope(models.PermShiftsManage))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if !ok {
+		app.errorResponse(w, r, http.StatusNotFound, "USER_NOT_FOUND", "user not found", nil)
+		return
+	}
+
+	assignment, err := app.models.Shift.Assign(requester.OrgID, shiftID, input.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.errorResponse(w, r, http.StatusNotFound, "SHIFT_NOT_FOUND", "shift not found", nil)
+		default:
+			app.assignmentErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusCreated, map[string]any{"assignment": assignment}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) DeleteShiftAssignmentHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	shiftID, err := app.readUUIDParam(r, "shiftID", "shift id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	userID, err := app.readUUIDParam(r, "userID", "user id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.models.Shift.Unassign(requester.OrgID, shiftID, userID); err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.errorResponse(w, r, http.StatusNotFound, "ASSIGNMENT_NOT_FOUND", "shift assignment not found", nil)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusNoContent, nil, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// ListMyShiftsHandler lists the requester's shifts as last published, so
+// drafts stay invisible to employees until they are published.
+func (app *Application) ListMyShiftsHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	from, to, err := app.readTimeRange(r.URL.Query(), maxResolveSpan)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	shifts, err := app.models.Shift.GetForUser(requester.UserID, from, to)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"shifts": shifts}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// assignmentErrorResponse maps the staffing rule violations returned by the
+// shift model to their dedicated error codes.
+func (app *Application) assignmentErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, models.ErrShiftFull):
+		app.shiftFullResponse(w, r)
+	case errors.Is(err, models.ErrAlreadyAssigned):
+		app.alreadyAssignedResponse(w, r)
+	case errors.Is(err, models.ErrDoubleBooking):
+		app.doubleBookingResponse(w, r)
+	case errors.Is(err, models.ErrOutsideAvailability):
+		app.outsideAvailabilityResponse(w, r)
+	case errors.Is(err, models.ErrUserInactive):
+		app.userInactiveResponse(w, r)
+	default:
+		app.internalServerError(w, r, err)
+	}
+}
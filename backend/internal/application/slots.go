@@ -0,0 +1,99 @@
+package application
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jonathanhu237/when-works/backend/internal/models"
+)
+
+func (app *Application) FindSlotsHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	var input struct {
+		UserIDs            []uuid.UUID `json:"user_ids" validate:"required,min=1,max=50,unique,dive,required"`
+		From               time.Time   `json:"from" validate:"required"`
+		To                 time.Time   `json:"to" validate:"required,gtfield=From"`
+		MinDurationMinutes int         `json:"min_duration_minutes" validate:"required,min=5,max=1440"`
+		GranularityMinutes int         `json:"granularity_minutes" validate:"required,min=5,max=1440"`
+		Limit              int         `json:"limit" validate:"omitempty,min=1,max=100"`
+	}
+
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.validator.Struct(input); err != nil {
+		app.failedValidationResponse(w, r, err)
+		return
+	}
+
+	from, to := input.From.UTC(), input.To.UTC()
+	if to.Sub(from) > maxResolveSpan {
+		app.badRequestResponse(w, r, errors.New("range must not exceed 93 days"))
+		return
+	}
+
+	if input.Limit == 0 {
+		input.Limit = 20
+	}
+
+	// Make sure every requested user exists before resolving anything. Users
+	// outside the requester's scope are reported as missing, but everyone may
+	// include themselves
+	users, err := app.models.User.GetManyInScope(requester.Scope(models.PermUsersRead), input.UserIDs)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	found := make(map[uuid.UUID]bool, len(users)+1)
+	found[requester.UserID] = true
+	for _, user := range users {
+		found[user.ID] = true
+	}
+
+	missing := []uuid.UUID{}
+	for _, userID := range input.UserIDs {
+		if !found[userID] {
+			missing = append(missing, userID)
+		}
+	}
+
+	if len(missing) > 0 {
+		app.errorResponse(w, r, http.StatusNotFound, "USER_NOT_FOUND", "one or more users could not be found", map[string]any{"user_ids": missing})
+		return
+	}
+
+	availability, err := app.models.Availability.ResolveMany(input.UserIDs, from, to)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.errorResponse(w, r, http.StatusNotFound, "USER_NOT_FOUND", "one or more users could not be found", nil)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	slots := models.FindSlots(
+		availability,
+		input.UserIDs,
+		from,
+		to,
+		time.Duration(input.MinDurationMinutes)*time.Minute,
+		time.Duration(input.GranularityMinutes)*time.Minute,
+		input.Limit,
+	)
+
+	response := map[string]any{
+		"total_users": len(input.UserIDs),
+		"slots":       slots,
+	}
+	if err := app.writeJSON(w, http.StatusOK, response, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
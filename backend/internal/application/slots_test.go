@@ -0,0 +1,64 @@
+package application
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jonathanhu237/when-works/backend/internal/models"
+)
+
+func TestFindSlotsRespectsTeamScope(t *testing.T) {
+	app := newTestApplication(t)
+	org, _ := createOrganization(t, app, "Acme", "owner")
+	manager := createUser(t, app, org.ID, "manager")
+	member := createUser(t, app, org.ID, "member")
+	outsider := createUser(t, app, org.ID, "outsider")
+
+	team := &models.Team{OrgID: org.ID, Name: "Kitchen"}
+	if err := app.models.Team.Insert(team); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.models.Team.SetMember(org.ID, team.ID, manager.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.models.Team.SetMember(org.ID, team.ID, member.ID, false); err != nil {
+		t.Fatal(err)
+	}
+
+	cookies := login(t, app, "manager")
+	from := time.Date(2025, time.March, 3, 0, 0, 0, 0, time.UTC)
+	input := func(userIDs ...uuid.UUID) map[string]any {
+		return map[string]any{
+			"user_ids":             userIDs,
+			"from":                 from,
+			"to":                   from.AddDate(0, 0, 7),
+			"min_duration_minutes": 60,
+			"granularity_minutes":  30,
+		}
+	}
+
+	res := serve(app, http.MethodPost, "/v1/availability/slots", jsonBody(t, input(manager.ID, member.ID)), cookies)
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("own team: status %d, want %d", res.StatusCode, http.StatusOK)
+	}
+
+	res = serve(app, http.MethodPost, "/v1/availability/slots", jsonBody(t, input(member.ID, outsider.ID)), cookies)
+	if res.StatusCode != http.StatusNotFound {
+		t.Fatalf("user outside the team: status %d, want %d", res.StatusCode, http.StatusNotFound)
+	}
+
+	var body struct {
+		Details struct {
+			UserIDs []uuid.UUID `json:"user_ids"`
+		} `json:"details"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if ids := body.Details.UserIDs; len(ids) != 1 || ids[0] != outsider.ID {
+		t.Errorf("missing user_ids = %v, want [%s]", ids, outsider.ID)
+	}
+}
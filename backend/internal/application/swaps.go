@@ -0,0 +1,297 @@
+package application
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jonathanhu237/when-works/backend/internal/models"
+)
+
+var swapMessages = map[models.SwapStatus]string{
+	models.SwapOpen:      "A colleague has offered you a shift swap.",
+	models.SwapAccepted:  "Your shift swap offer was accepted and is waiting for approval.",
+	models.SwapApproved:  "A shift swap you are part of was approved and your shifts have been updated.",
+	models.SwapRejected:  "A shift swap you are part of was rejected. Your shifts are unchanged.",
+	models.SwapCancelled: "A shift swap offered to you was cancelled.",
+}
+
+func (app *Application) CreateMySwapHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	var input struct {
+		ShiftID       uuid.UUID  `json:"shift_id" validate:"required"`
+		Kind          string     `json:"kind" validate:"required,oneof=giveaway swap"`
+		TargetShiftID *uuid.UUID `json:"target_shift_id" validate:"required_if=Kind swap,excluded_if=Kind giveaway"`
+		TargetUserID  *uuid.UUID `json:"target_user_id" validate:"required_if=Kind swap"`
+		Note          string     `json:"note" validate:"max=500"`
+	}
+
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.validator.Struct(input); err != nil {
+		app.failedValidationResponse(w, r, err)
+		return
+	}
+
+	if input.TargetUserID != nil && *input.TargetUserID == requester.UserID {
+		app.badRequestResponse(w, r, errors.New("target user must be someone else"))
+		return
+	}
+	if input.TargetShiftID != nil && *input.TargetShiftID == input.ShiftID {
+		app.badRequestResponse(w, r, errors.New("target shift must be a different shift"))
+		return
+	}
+
+	swap := &models.Swap{
+		Kind:          models.SwapKind(input.Kind),
+		ShiftID:       input.ShiftID,
+		RequesterID:   requester.UserID,
+		TargetShiftID: input.TargetShiftID,
+		TargetUserID:  input.TargetUserID,
+		Note:          input.Note,
+	}
+
+	if err := app.models.Swap.Insert(requester.OrgID, swap); err != nil {
+		switch {
+		case errors.Is(err, models.ErrNotAssigned):
+			app.errorResponse(w, r, http.StatusConflict, "SWAP_NOT_ASSIGNED", "the offered or requested shift is not worked by the expected user", nil)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.notifySwap(requester.OrgID, swap, models.SwapOpen, requester.UserID)
+
+	if err := app.writeJSON(w, http.StatusCreated, map[string]any{"swap": swap}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) ListMySwapsHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	swaps, err := app.models.Swap.GetForUser(requester.UserID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"swaps": swaps}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) CancelMySwapHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	swapID, err := app.readUUIDParam(r, "swapID", "swap id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.models.Swap.Cancel(requester.OrgID, swapID, requester.UserID); err != nil {
+		app.swapErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeSwap(w, r, swapID, models.SwapCancelled, requester.UserID)
+}
+
+func (app *Application) ListOpenSwapsHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	swaps, err := app.models.Swap.GetOpenFor(requester.OrgID, requester.UserID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"swaps": swaps}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) ListPendingSwapsHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	swaps, err := app.models.Swap.GetByStatus(requester.OrgID, models.SwapAccepted)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"swaps": swaps}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) GetSwapHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	swapID, err := app.readUUIDParam(r, "swapID", "swap id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	swap, err := app.models.Swap.GetByID(requester.OrgID, swapID)
+	if err != nil {
+		app.swapErrorResponse(w, r, err)
+		return
+	}
+
+	// Open giveaways are visible to everyone who could take them
+	visible := requester.Can(models.PermSwapsRead) || slices.Contains(swap.Parties(), requester.UserID) ||
+		(swap.Status == models.SwapOpen && swap.TargetUserID == nil)
+	if !visible {
+		app.forbiddenResponse(w, r)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"swap": swap}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) AcceptSwapHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	swapID, err := app.readUUIDParam(r, "swapID", "swap id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	status, err := app.models.Swap.Accept(requester.OrgID, swapID, requester.UserID, app.config.Swap.AutoApprove)
+	if err != nil {
+		app.swapErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeSwap(w, r, swapID, status, requester.UserID)
+}
+
+func (app *Application) ApproveSwapHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	swapID, err := app.readUUIDParam(r, "swapID", "swap id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.models.Swap.Approve(requester.OrgID, swapID, requester.UserID); err != nil {
+		app.swapErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeSwap(w, r, swapID, models.SwapApproved, requester.UserID)
+}
+
+func (app *Application) RejectSwapHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	swapID, err := app.readUUIDParam(r, "swapID", "swap id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.models.Swap.Reject(requester.OrgID, swapID, requester.UserID); err != nil {
+		app.swapErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeSwap(w, r, swapID, models.SwapRejected, requester.UserID)
+}
+
+// writeSwap reloads the swap after a transition, notifies the other parties
+// and writes it to the response.
+func (app *Application) writeSwap(w http.ResponseWriter, r *http.Request, swapID uuid.UUID, status models.SwapStatus, actorID uuid.UUID) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	swap, err := app.models.Swap.GetByID(requester.OrgID, swapID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.notifySwap(requester.OrgID, swap, status, actorID)
+
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"swap": swap}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) swapErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, models.ErrRecordNotFound):
+		app.errorResponse(w, r, http.StatusNotFound, "SWAP_NOT_FOUND", "swap not found", nil)
+	case errors.Is(err, models.ErrSwapNotAllowed):
+		app.forbiddenResponse(w, r)
+	case errors.Is(err, models.ErrSwapInvalidStatus):
+		app.errorResponse(w, r, http.StatusConflict, "SWAP_INVALID_STATUS", "the swap is not in a state that allows this action", nil)
+	case errors.Is(err, models.ErrNotAssigned):
+		app.errorResponse(w, r, http.StatusConflict, "SWAP_NOT_ASSIGNED", "the shifts in this swap are no longer worked by the expected users", nil)
+	default:
+		app.assignmentErrorResponse(w, r, err)
+	}
+}
+
+// notifySwap emails every party of the swap except the one who acted.
+func (app *Application) notifySwap(orgID uuid.UUID, swap *models.Swap, status models.SwapStatus, actorID uuid.UUID) {
+	var recipients []uuid.UUID
+	for _, userID := range swap.Parties() {
+		if userID != actorID {
+			recipients = append(recipients, userID)
+		}
+	}
+
+	if len(recipients) == 0 {
+		return
+	}
+
+	app.background(func() {
+		shift, err := app.models.Shift.GetByID(orgID, swap.ShiftID)
+		if err != nil {
+			app.logger.Error("failed to load shift for swap notification", "error", err)
+			return
+		}
+
+		users, err := app.models.User.GetManyByID(orgID, recipients)
+		if err != nil {
+			app.logger.Error("failed to load users for swap notification", "error", err)
+			return
+		}
+
+		for _, user := range users {
+			location, err := time.LoadLocation(user.TimeZone)
+			if err != nil {
+				location = time.UTC
+			}
+
+			data := map[string]any{
+				"name":       user.Name,
+				"message":    swapMessages[status],
+				"shiftTitle": shift.Title,
+				"shiftStart": shift.StartAt.In(location).Format("Mon Jan 2 2006, 15:04 MST"),
+				"shiftEnd":   shift.EndAt.In(location).Format("Mon Jan 2 2006, 15:04 MST"),
+				"status":     string(status),
+			}
+
+			if err := app.mailer.SendHTML(user.Email, "WhenWorks Shift Swap Update", "swap_update.html", data); err != nil {
+				app.logger.Error("failed to send swap update email", "error", err, "email", user.Email)
+				continue
+			}
+			app.logger.Info("swap update email sent", "email", user.Email)
+		}
+	})
+}
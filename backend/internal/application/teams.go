@@ -0,0 +1,314 @@
+package application
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/jonathanhu237/when-works/backend/internal/models"
+)
+
+func (app *Application) teamErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, models.ErrRecordNotFound):
+		app.errorResponse(w, r, http.StatusNotFound, "TEAM_NOT_FOUND", "team not found", nil)
+	case errors.Is(err, models.ErrTeamNameConflict):
+		app.errorResponse(w, r, http.StatusConflict, "TEAM_NAME_CONFLICT", "team name already exists", nil)
+	default:
+		app.internalServerError(w, r, err)
+	}
+}
+
+// canSeeTeam reports whether the requester may see the team and its members:
+// those who can read all users see every team, managers their own.
+func (ri *RequesterInfo) canSeeTeam(teamID uuid.UUID) bool {
+	return ri.Can(models.PermUsersRead) || slices.Contains(ri.ManagedTeamIDs, teamID)
+}
+
+func (app *Application) ListTeamsHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	var teams []models.Team
+	if requester.Can(models.PermUsersRead) {
+		var err error
+		teams, err = app.models.Team.GetAll(requester.OrgID)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+	} else {
+		memberships, err := app.models.Team.GetForUser(requester.UserID)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		teams = []models.Team{}
+		for _, membership := range memberships {
+			if membership.IsManager {
+				teams = append(teams, membership.Team)
+			}
+		}
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"teams": teams}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) GetTeamHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	teamID, err := app.readUUIDParam(r, "teamID", "team id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if !requester.canSeeTeam(teamID) {
+		app.teamErrorResponse(w, r, models.ErrRecordNotFound)
+		return
+	}
+
+	team, err := app.models.Team.GetByID(requester.OrgID, teamID)
+	if err != nil {
+		app.teamErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"team": team}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) CreateTeamHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	var input struct {
+		Name        string `json:"name" validate:"required,max=100"`
+		Description string `json:"description" validate:"max=500"`
+	}
+
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.validator.Struct(input); err != nil {
+		app.failedValidationResponse(w, r, err)
+		return
+	}
+
+	team := &models.Team{
+		OrgID:       requester.OrgID,
+		Name:        input.Name,
+		Description: input.Description,
+	}
+
+	if err := app.models.Team.Insert(team); err != nil {
+		app.teamErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusCreated, map[string]any{"team": team}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) UpdateTeamHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	teamID, err := app.readUUIDParam(r, "teamID", "team id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var input struct {
+		Name        *string `json:"name" validate:"omitempty,min=1,max=100"`
+		Description *string `json:"description" validate:"omitempty,max=500"`
+	}
+
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Name == nil && input.Description == nil {
+		app.badRequestResponse(w, r, errors.New("at least one field must be provided"))
+		return
+	}
+
+	if err := app.validator.Struct(input); err != nil {
+		app.failedValidationResponse(w, r, err)
+		return
+	}
+
+	team, err := app.models.Team.GetByID(requester.OrgID, teamID)
+	if err != nil {
+		app.teamErrorResponse(w, r, err)
+		return
+	}
+
+	if input.Name != nil {
+		team.Name = *input.Name
+	}
+	if input.Description != nil {
+		team.Description = *input.Description
+	}
+
+	if err := app.models.Team.Update(team); err != nil {
+		app.teamErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"team": team}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) DeleteTeamHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	teamID, err := app.readUUIDParam(r, "teamID", "team id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.models.Team.Delete(requester.OrgID, teamID); err != nil {
+		app.teamErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusNoContent, nil, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) ListTeamMembersHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	teamID, err := app.readUUIDParam(r, "teamID", "team id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if !requester.canSeeTeam(teamID) {
+		app.teamErrorResponse(w, r, models.ErrRecordNotFound)
+		return
+	}
+
+	if _, err := app.models.Team.GetByID(requester.OrgID, teamID); err != nil {
+		app.teamErrorResponse(w, r, err)
+		return
+	}
+
+	members, err := app.models.Team.GetMembers(teamID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"members": members}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// SetTeamMemberHandler adds a user to a team or changes whether they manage
+// it. Managers gain access to the team's members from their next request.
+func (app *Application) SetTeamMemberHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	teamID, err := app.readUUIDParam(r, "teamID", "team id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	userID, err := app.readUUIDParam(r, "userID", "user id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var input struct {
+		IsManager bool `json:"is_manager"`
+	}
+
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if _, err := app.models.Team.GetByID(requester.OrgID, teamID); err != nil {
+		app.teamErrorResponse(w, r, err)
+		return
+	}
+
+	inOrg, err := app.models.User.InScope(userID, models.AllUsers(requester.OrgID))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if !inOrg {
+		app.errorResponse(w, r, http.StatusNotFound, "USER_NOT_FOUND", "user not found", nil)
+		return
+	}
+
+	if err := app.models.Team.SetMember(requester.OrgID, teamID, userID, input.IsManager); err != nil {
+		// The team or user was deleted in the meantime
+		app.teamErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusNoContent, nil, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) RemoveTeamMemberHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	teamID, err := app.readUUIDParam(r, "teamID", "team id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	userID, err := app.readUUIDParam(r, "userID", "user id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.models.Team.RemoveMember(requester.OrgID, teamID, userID); err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.errorResponse(w, r, http.StatusNotFound, "TEAM_MEMBER_NOT_FOUND", "the user is not a member of the team", nil)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusNoContent, nil, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) ListMyTeamsHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	memberships, err := app.models.Team.GetForUser(requester.UserID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"teams": memberships}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
@@ -0,0 +1,284 @@
+package application
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jonathanhu237/when-works/backend/internal/models"
+)
+
+type timeOffInput struct {
+	StartDate string `json:"start_date" validate:"required,datetime=2006-01-02"`
+	EndDate   string `json:"end_date" validate:"required,datetime=2006-01-02"`
+	Reason    string `json:"reason" validate:"max=500"`
+}
+
+type timeOffDecisionInput struct {
+	Note string `json:"note" validate:"max=500"`
+}
+
+func (app *Application) CreateMyTimeOffHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	var input timeOffInput
+
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.validator.Struct(input); err != nil {
+		app.failedValidationResponse(w, r, err)
+		return
+	}
+
+	request := &models.TimeOffRequest{
+		UserID:    requester.UserID,
+		StartDate: input.StartDate,
+		EndDate:   input.EndDate,
+		Reason:    input.Reason,
+	}
+
+	if err := app.models.TimeOff.Insert(request); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusCreated, map[string]any{"time_off": request}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) ListMyTimeOffHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	requests, err := app.models.TimeOff.GetForUser(requester.UserID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"time_off": requests}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) CancelMyTimeOffHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	requestID, err := app.readUUIDParam(r, "timeOffID", "time off id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.models.TimeOff.Cancel(requester.OrgID, requestID, requester.UserID); err != nil {
+		app.timeOffErrorResponse(w, r, err)
+		return
+	}
+
+	request, err := app.models.TimeOff.GetByID(requester.OrgID, requestID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"time_off": request}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) ListTimeOffHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	status := models.TimeOffStatus(r.URL.Query().Get("status"))
+	switch status {
+	case "", models.TimeOffPending, models.TimeOffApproved, models.TimeOffDenied, models.TimeOffCancelled:
+	default:
+		app.badRequestResponse(w, r, errors.New("status must be one of pending, approved, denied or cancelled"))
+		return
+	}
+
+	requests, err := app.models.TimeOff.GetAll(requester.OrgID, status)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"time_off": requests}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) GetTimeOffHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	requestID, err := app.readUUIDParam(r, "timeOffID", "time off id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	request, err := app.models.TimeOff.GetByID(requester.OrgID, requestID)
+	if err != nil {
+		app.timeOffErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"time_off": request}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// ApproveTimeOffHandler approves the request and returns the shifts the user
+// is still assigned to during the time off, so an admin can reassign them.
+func (app *Application) ApproveTimeOffHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	requestID, err := app.readUUIDParam(r, "timeOffID", "time off id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var input timeOffDecisionInput
+
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.validator.Struct(input); err != nil {
+		app.failedValidationResponse(w, r, err)
+		return
+	}
+
+	exception, err := app.models.TimeOff.Approve(requester.OrgID, requestID, requester.UserID, input.Note)
+	if err != nil {
+		app.timeOffErrorResponse(w, r, err)
+		return
+	}
+
+	request, err := app.models.TimeOff.GetByID(requester.OrgID, requestID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	conflicts, err := app.models.Shift.GetLiveForUser(request.UserID, exception.StartAt, exception.EndAt)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.notifyTimeOff(requester.OrgID, request, conflicts)
+
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"time_off": request, "conflicts": conflicts}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) DenyTimeOffHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	requestID, err := app.readUUIDParam(r, "timeOffID", "time off id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var input timeOffDecisionInput
+
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.validator.Struct(input); err != nil {
+		app.failedValidationResponse(w, r, err)
+		return
+	}
+
+	if err := app.models.TimeOff.Deny(requester.OrgID, requestID, requester.UserID, input.Note); err != nil {
+		app.timeOffErrorResponse(w, r, err)
+		return
+	}
+
+	request, err := app.models.TimeOff.GetByID(requester.OrgID, requestID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.notifyTimeOff(requester.OrgID, request, nil)
+
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"time_off": request}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) timeOffErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, models.ErrRecordNotFound):
+		app.errorResponse(w, r, http.StatusNotFound, "TIME_OFF_NOT_FOUND", "time off request not found", nil)
+	case errors.Is(err, models.ErrTimeOffInvalidStatus):
+		app.errorResponse(w, r, http.StatusConflict, "TIME_OFF_NOT_PENDING", "the time off request has already been decided or cancelled", nil)
+	default:
+		app.internalServerError(w, r, err)
+	}
+}
+
+// notifyTimeOff emails the requester the decision. Conflicts are listed in the
+// approval email so they know which shifts still need cover.
+func (app *Application) notifyTimeOff(orgID uuid.UUID, request *models.TimeOffRequest, conflicts []models.Shift) {
+	app.background(func() {
+		users, err := app.models.User.GetManyByID(orgID, []uuid.UUID{request.UserID})
+		if err != nil || len(users) == 0 {
+			app.logger.Error("failed to load user for time off notification", "error", err, "user_id", request.UserID)
+			return
+		}
+		user := users[0]
+
+		location, err := time.LoadLocation(user.TimeZone)
+		if err != nil {
+			location = time.UTC
+		}
+
+		type conflictData struct {
+			Title string
+			Start string
+			End   string
+		}
+
+		conflictRows := make([]conflictData, 0, len(conflicts))
+		for _, shift := range conflicts {
+			conflictRows = append(conflictRows, conflictData{
+				Title: shift.Title,
+				Start: shift.StartAt.In(location).Format("Mon Jan 2 2006, 15:04"),
+				End:   shift.EndAt.In(location).Format("Mon Jan 2 2006, 15:04"),
+			})
+		}
+
+		data := map[string]any{
+			"name":      user.Name,
+			"startDate": request.StartDate,
+			"endDate":   request.EndDate,
+			"reason":    request.Reason,
+			"note":      request.DecisionNote,
+			"conflicts": conflictRows,
+		}
+
+		subject, template := "Your WhenWorks Time Off Was Approved", "time_off_approved.html"
+		if request.Status == models.TimeOffDenied {
+			subject, template = "Your WhenWorks Time Off Was Denied", "time_off_denied.html"
+		}
+
+		if err := app.mailer.SendHTML(user.Email, subject, template, data); err != nil {
+			app.logger.Error("failed to send time off email", "error", err, "email", user.Email)
+			return
+		}
+		app.logger.Info("time off email sent", "email", user.Email, "status", request.Status)
+	})
+}
@@ -0,0 +1,371 @@
+package application
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jonathanhu237/when-works/backend/internal/models"
+	"github.com/jonathanhu237/when-works/backend/internal/totp"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const totpChallengeTTL = 5 * time.Minute
+
+func (app *Application) invalidTOTPCodeResponse(w http.ResponseWriter, r *http.Request) {
+	app.errorResponse(w, r, http.StatusUnauthorized, "INVALID_TOTP_CODE", "invalid authentication code", nil)
+}
+
+// invalidTOTPResponse counts a wrong second factor as a failed login, since
+// whoever sent it already knew the password.
+func (app *Application) invalidTOTPResponse(w http.ResponseWriter, r *http.Request, username string) {
+	wait, err := app.loginFailed(r.Context(), username, app.clientIP(r))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if wait > 0 {
+		app.loginLockedResponse(w, r, wait)
+		return
+	}
+
+	app.invalidTOTPCodeResponse(w, r)
+}
+
+// checkPassword re-authenticates the user before a sensitive change and
+// writes the error response if the password is wrong.
+func (app *Application) checkPassword(w http.ResponseWriter, r *http.Request, userID uuid.UUID, password string) bool {
+	user, err := app.models.User.GetByID(userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.errorResponse(w, r, http.StatusNotFound, "USER_NOT_FOUND", "user not found", nil)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return false
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		app.errorResponse(w, r, http.StatusUnauthorized, "INVALID_PASSWORD", "password is incorrect", nil)
+		return false
+	}
+
+	return true
+}
+
+// LoginTOTPHandler completes a login that LoginHandler answered with a
+// challenge, using a code from the authenticator app or a recovery code. A
+// challenge allows a single attempt; after a wrong code the user signs in
+// with their password again.
+func (app *Application) LoginTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Challenge    string `json:"challenge" validate:"required"`
+		Code         string `json:"code" validate:"required_without=RecoveryCode,excluded_with=RecoveryCode,omitempty,len=6,numeric"`
+		RecoveryCode string `json:"recovery_code" validate:"required_without=Code"`
+	}
+
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.validator.Struct(input); err != nil {
+		app.failedValidationResponse(w, r, err)
+		return
+	}
+
+	userID, err := app.models.Token.Consume(models.ScopeTOTPChallenge, input.Challenge)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrInvalidToken):
+			app.invalidTokenResponse(w, r)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	user, err := app.models.User.GetByID(userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.invalidTokenResponse(w, r)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if user.Status != models.UserStatusActive {
+		app.errorResponse(w, r, http.StatusForbidden, "USER_NOT_ACTIVE", "the account has not been activated", nil)
+		return
+	}
+
+	if input.RecoveryCode != "" {
+		if err := app.models.TOTP.UseRecoveryCode(user.ID, input.RecoveryCode); err != nil {
+			switch {
+			case errors.Is(err, models.ErrRecordNotFound):
+				app.invalidTOTPResponse(w, r, user.Username)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+	} else {
+		enrollment, err := app.models.TOTP.Get(user.ID)
+		if err != nil {
+			switch {
+			// Disabled after the challenge was issued
+			case errors.Is(err, models.ErrRecordNotFound):
+				app.invalidTokenResponse(w, r)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		counter, ok := totp.Validate(enrollment.Secret, input.Code, time.Now())
+		if !ok {
+			app.invalidTOTPResponse(w, r, user.Username)
+			return
+		}
+
+		if err := app.models.TOTP.UseCounter(user.ID, counter); err != nil {
+			switch {
+			case errors.Is(err, models.ErrTOTPCodeReused):
+				app.invalidTOTPResponse(w, r, user.Username)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+	}
+
+	if err := app.startSession(w, r, user); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"user": user}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) GetMyTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	enrollment, err := app.models.TOTP.Get(requester.UserID)
+	if err != nil && !errors.Is(err, models.ErrRecordNotFound) {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := map[string]any{"enabled": false}
+	if enrollment != nil && enrollment.Enabled() {
+		left, err := app.models.TOTP.RecoveryCodesLeft(requester.UserID)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		response = map[string]any{
+			"enabled":              true,
+			"confirmed_at":         enrollment.ConfirmedAt,
+			"recovery_codes_count": left,
+		}
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, response, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// BeginMyTOTPHandler generates a secret for the requester's authenticator app.
+// Two-factor authentication is not on until ConfirmMyTOTPHandler succeeds.
+func (app *Application) BeginMyTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.models.TOTP.Begin(requester.UserID, secret); err != nil {
+		switch {
+		case errors.Is(err, models.ErrTOTPAlreadyEnabled):
+			app.errorResponse(w, r, http.StatusConflict, "TOTP_ALREADY_ENABLED", "two-factor authentication is already enabled", nil)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	response := map[string]any{
+		"secret": secret,
+		"uri":    totp.URI(app.config.TOTP.Issuer, requester.Username, secret),
+	}
+	if err := app.writeJSON(w, http.StatusCreated, response, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) ConfirmMyTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	var input struct {
+		Code string `json:"code" validate:"required,len=6,numeric"`
+	}
+
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.validator.Struct(input); err != nil {
+		app.failedValidationResponse(w, r, err)
+		return
+	}
+
+	enrollment, err := app.models.TOTP.Get(requester.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.errorResponse(w, r, http.StatusNotFound, "TOTP_NOT_FOUND", "two-factor authentication has not been set up", nil)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if enrollment.Enabled() {
+		app.errorResponse(w, r, http.StatusConflict, "TOTP_ALREADY_ENABLED", "two-factor authentication is already enabled", nil)
+		return
+	}
+
+	counter, ok := totp.Validate(enrollment.Secret, input.Code, time.Now())
+	if !ok {
+		app.invalidTOTPCodeResponse(w, r)
+		return
+	}
+
+	codes, err := app.models.TOTP.Confirm(requester.UserID, counter)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrTOTPAlreadyEnabled):
+			app.errorResponse(w, r, http.StatusConflict, "TOTP_ALREADY_ENABLED", "two-factor authentication is already enabled", nil)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"recovery_codes": codes}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) RegenerateMyRecoveryCodesHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	var input struct {
+		Password string `json:"password" validate:"required"`
+	}
+
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.validator.Struct(input); err != nil {
+		app.failedValidationResponse(w, r, err)
+		return
+	}
+
+	if !app.checkPassword(w, r, requester.UserID, input.Password) {
+		return
+	}
+
+	enrollment, err := app.models.TOTP.Get(requester.UserID)
+	if err != nil && !errors.Is(err, models.ErrRecordNotFound) {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if enrollment == nil || !enrollment.Enabled() {
+		app.errorResponse(w, r, http.StatusNotFound, "TOTP_NOT_FOUND", "two-factor authentication is not enabled", nil)
+		return
+	}
+
+	codes, err := app.models.TOTP.RegenerateRecoveryCodes(requester.UserID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"recovery_codes": codes}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *Application) DisableMyTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	var input struct {
+		Password string `json:"password" validate:"required"`
+	}
+
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.validator.Struct(input); err != nil {
+		app.failedValidationResponse(w, r, err)
+		return
+	}
+
+	if !app.checkPassword(w, r, requester.UserID, input.Password) {
+		return
+	}
+
+	if err := app.models.TOTP.Disable(requester.UserID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusNoContent, nil, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// DisableUserTOTPHandler lets an admin turn off two-factor authentication for
+// a user who has lost both their authenticator and their recovery codes.
+func (app *Application) DisableUserTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := app.readUUIDParam(r, "userID", "user id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if _, err := app.models.User.GetByID(userID); err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.errorResponse(w, r, http.StatusNotFound, "USER_NOT_FOUND", "user not found", nil)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.models.TOTP.Disable(userID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusNoContent, nil, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
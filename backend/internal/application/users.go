@@ -3,6 +3,8 @@ package application
 import (
 	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
@@ -10,7 +12,28 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+const (
+	defaultUserPageSize = 50
+	maxUserPageSize     = 100
+)
+
+// userListInput holds the query parameters of the user list as given, so
+// that malformed values are reported like any other validation failure.
+type userListInput struct {
+	Search        string `validate:"max=100"`
+	Status        string `validate:"omitempty,oneof=invited active deactivated archived"`
+	Role          string `validate:"max=100"`
+	Team          string `validate:"omitempty,uuid"`
+	CreatedAfter  string `validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	CreatedBefore string `validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	Sort          string `validate:"oneof=created_at -created_at username -username name -name email -email"`
+	Limit         string
+	Cursor        string
+}
+
 func (app *Application) CreateUserHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
 	var input struct {
 		Username string `json:"username" validate:"required"`
 		Email    string `json:"email" validate:"required,email"`
@@ -27,21 +50,13 @@ func (app *Application) CreateUserHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	// Generate a random password
-	password := app.generatePassword()
-	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		app.internalServerError(w, r, err)
-		return
-	}
-
-	// Create user
+	// Create the user without a password; they choose one when activating
 	user := &models.User{
-		Username:     input.Username,
-		Email:        input.Email,
-		Name:         input.Name,
-		PasswordHash: string(passwordHash),
-		IsAdmin:      false,
+		OrgID:    requester.OrgID,
+		Username: input.Username,
+		Email:    input.Email,
+		Name:     input.Name,
+		Status:   models.UserStatusInvited,
 	}
 
 	if err := app.models.User.Insert(user); err != nil {
@@ -56,20 +71,10 @@ func (app *Application) CreateUserHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	// Send email in background
-	app.background(func() {
-		data := map[string]any{
-			"name":     user.Name,
-			"username": user.Username,
-			"password": password,
-		}
-
-		if err := app.mailer.SendHTML(user.Email, "Welcome to WhenWorks", "welcome.html", data); err != nil {
-			app.logger.Error("failed to send welcome email to new user", "error", err)
-			return
-		}
-		app.logger.Info("welcome email sent to new user", "email", user.Email)
-	})
+	if err := app.sendInvitation(user); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
 
 	// Return created user
 	if err := app.writeJSON(w, http.StatusCreated, map[string]any{"user": user}, nil); err != nil {
@@ -77,14 +82,68 @@ func (app *Application) CreateUserHandler(w http.ResponseWriter, r *http.Request
 	}
 }
 
+// ListUsersHandler returns a page of the users the requester may see. The
+// next page is requested by passing the returned next_cursor as cursor, with
+// the same filters and sort.
 func (app *Application) ListUsersHandler(w http.ResponseWriter, r *http.Request) {
-	users, err := app.models.User.GetAll()
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	qs := r.URL.Query()
+	input := userListInput{
+		Search:        qs.Get("search"),
+		Status:        qs.Get("status"),
+		Role:          qs.Get("role"),
+		Team:          qs.Get("team"),
+		CreatedAfter:  qs.Get("created_after"),
+		CreatedBefore: qs.Get("created_before"),
+		Sort:          qs.Get("sort"),
+		Limit:         qs.Get("limit"),
+		Cursor:        qs.Get("cursor"),
+	}
+	if input.Sort == "" {
+		input.Sort = "-created_at"
+	}
+	if input.Limit == "" {
+		input.Limit = strconv.Itoa(defaultUserPageSize)
+	}
+
+	if err := app.validator.Struct(input); err != nil {
+		app.failedValidationResponse(w, r, err)
+		return
+	}
+
+	// The rules above guarantee every value parses
+	filter := models.UserFilter{Search: input.Search, Status: input.Status, Role: input.Role, Sort: input.Sort}
+	filter.Limit, _ = strconv.Atoi(input.Limit)
+	if input.Team != "" {
+		teamID := uuid.MustParse(input.Team)
+		filter.TeamID = &teamID
+	}
+	if input.CreatedAfter != "" {
+		createdAfter, _ := time.Parse(time.RFC3339, input.CreatedAfter)
+		filter.CreatedAfter = &createdAfter
+	}
+	if input.CreatedBefore != "" {
+		createdBefore, _ := time.Parse(time.RFC3339, input.CreatedBefore)
+		filter.CreatedBefore = &createdBefore
+	}
+	if input.Cursor != "" {
+		filter.After, _ = models.DecodeUserCursor(input.Cursor)
+	}
+
+	users, next, err := app.models.User.List(requester.Scope(models.PermUsersRead), filter)
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 
-	response := map[string]any{"users": users}
+	var nextCursor *string
+	if next != nil {
+		encoded := next.Encode()
+		nextCursor = &encoded
+	}
+
+	response := map[string]any{"users": users, "next_cursor": nextCursor}
 	if err := app.writeJSON(w, http.StatusOK, response, nil); err != nil {
 		app.internalServerError(w, r, err)
 	}
@@ -143,6 +202,11 @@ func (app *Application) ResetUserPasswordHandler(w http.ResponseWriter, r *http.
 		return
 	}
 
+	if user.Status == models.UserStatusInvited {
+		app.userInvitedResponse(w, r)
+		return
+	}
+
 	password := app.generatePassword()
 	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
@@ -162,6 +226,17 @@ func (app *Application) ResetUserPasswordHandler(w http.ResponseWriter, r *http.
 		return
 	}
 
+	// The old password may be known to someone else, so sign out everywhere
+	// and drop the access tokens
+	if err := app.models.Session.RevokeAllForUser(user.ID, uuid.Nil); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if err := app.models.APIToken.RevokeAllForUser(user.ID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
 	app.background(func() {
 		data := map[string]any{
 			"name":     user.Name,
@@ -195,9 +270,8 @@ func (app *Application) UpdateUserHandler(w http.ResponseWriter, r *http.Request
 	}
 
 	var input struct {
-		Email   *string `json:"email" validate:"omitempty,email"`
-		Name    *string `json:"name" validate:"omitempty,min=1"`
-		IsAdmin *bool   `json:"is_admin"`
+		Email *string `json:"email" validate:"omitempty,email"`
+		Name  *string `json:"name" validate:"omitempty,min=1"`
 	}
 
 	if err := app.readJSON(w, r, &input); err != nil {
@@ -205,7 +279,7 @@ func (app *Application) UpdateUserHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	if input.Email == nil && input.Name == nil && input.IsAdmin == nil {
+	if input.Email == nil && input.Name == nil {
 		app.badRequestResponse(w, r, errors.New("at least one field must be provided"))
 		return
 	}
@@ -232,9 +306,6 @@ func (app *Application) UpdateUserHandler(w http.ResponseWriter, r *http.Request
 	if input.Name != nil {
 		user.Name = *input.Name
 	}
-	if input.IsAdmin != nil {
-		user.IsAdmin = *input.IsAdmin
-	}
 
 	if err := app.models.User.Update(user); err != nil {
 		switch {
@@ -253,25 +324,107 @@ func (app *Application) UpdateUserHandler(w http.ResponseWriter, r *http.Request
 	}
 }
 
+func (app *Application) userStatusErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, models.ErrRecordNotFound):
+		app.errorResponse(w, r, http.StatusNotFound, "USER_NOT_FOUND", "user not found", nil)
+	case errors.Is(err, models.ErrUserInvalidStatus):
+		app.errorResponse(w, r, http.StatusConflict, "USER_INVALID_STATUS", "the user is not in a state that allows this action", nil)
+	case errors.Is(err, models.ErrLastSuperadmin):
+		app.errorResponse(w, r, http.StatusConflict, "ROLE_LAST_SUPERADMIN", "at least one user must keep the superadmin role", nil)
+	default:
+		app.internalServerError(w, r, err)
+	}
+}
+
+// DeleteUserHandler archives the user: they can no longer sign in and are
+// left out of the lists, but their shifts and history stay until the user is
+// purged.
 func (app *Application) DeleteUserHandler(w http.ResponseWriter, r *http.Request) {
-	userIDParam := chi.URLParam(r, "userID")
-	if userIDParam == "" {
-		app.badRequestResponse(w, r, errors.New("user id is required"))
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	userID, err := app.readUUIDParam(r, "userID", "user id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
 		return
 	}
 
-	userID, err := uuid.Parse(userIDParam)
+	if err := app.models.User.Archive(requester.OrgID, userID); err != nil {
+		app.userStatusErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.models.Session.RevokeAllForUser(userID, uuid.Nil); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusNoContent, nil, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// DeactivateUserHandler stops the user from signing in and signs them out
+// everywhere, until they are restored.
+func (app *Application) DeactivateUserHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	userID, err := app.readUUIDParam(r, "userID", "user id")
 	if err != nil {
-		app.badRequestResponse(w, r, errors.New("invalid user id"))
+		app.badRequestResponse(w, r, err)
 		return
 	}
 
-	if err := app.models.User.Delete(userID); err != nil {
+	if err := app.models.User.Deactivate(requester.OrgID, userID); err != nil {
+		app.userStatusErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.models.Session.RevokeAllForUser(userID, uuid.Nil); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.writeUser(w, r, userID)
+}
+
+// RestoreUserHandler brings back a deactivated or archived user.
+func (app *Application) RestoreUserHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	userID, err := app.readUUIDParam(r, "userID", "user id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.models.User.Restore(requester.OrgID, userID); err != nil {
+		app.userStatusErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeUser(w, r, userID)
+}
+
+// PurgeUserHandler permanently deletes an archived user and everything that
+// belongs to them, once the grace period since archiving has passed.
+func (app *Application) PurgeUserHandler(w http.ResponseWriter, r *http.Request) {
+	requester := r.Context().Value(requesterContextKey).(*RequesterInfo)
+
+	userID, err := app.readUUIDParam(r, "userID", "user id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	gracePeriod := time.Duration(app.config.User.PurgeGracePeriod) * time.Second
+	purgeableAt, err := app.models.User.Purge(requester.OrgID, userID, gracePeriod)
+	if err != nil {
 		switch {
-		case errors.Is(err, models.ErrRecordNotFound):
-			app.errorResponse(w, r, http.StatusNotFound, "USER_NOT_FOUND", "user not found", nil)
+		case errors.Is(err, models.ErrUserPurgeTooEarly):
+			app.errorResponse(w, r, http.StatusConflict, "USER_PURGE_TOO_EARLY", "the user cannot be purged before the grace period has passed", map[string]any{"purgeable_at": purgeableAt})
 		default:
-			app.internalServerError(w, r, err)
+			app.userStatusErrorResponse(w, r, err)
 		}
 		return
 	}
@@ -280,3 +433,16 @@ func (app *Application) DeleteUserHandler(w http.ResponseWriter, r *http.Request
 		app.internalServerError(w, r, err)
 	}
 }
+
+// writeUser responds with the user as they are now.
+func (app *Application) writeUser(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	user, err := app.models.User.GetByID(userID)
+	if err != nil {
+		app.userStatusErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, map[string]any{"user": user}, nil); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
@@ -0,0 +1,141 @@
+package application
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jonathanhu237/when-works/backend/internal/models"
+)
+
+// registerValidations adds the custom tags and struct-level rules used by the
+// handler inputs, so that every rejection flows through failedValidationResponse.
+func (app *Application) registerValidations() error {
+	if err := app.validator.RegisterValidation("clock", validateClock); err != nil {
+		return err
+	}
+	if err := app.validator.RegisterValidation("bcrypt", validateBcrypt); err != nil {
+		return err
+	}
+
+	app.validator.RegisterStructValidation(validateAvailabilityWindow, availabilityWindowInput{})
+	app.validator.RegisterStructValidation(validateAvailability, availabilityInput{})
+	app.validator.RegisterStructValidation(validateTimeOff, timeOffInput{})
+	app.validator.RegisterStructValidation(validateUserList, userListInput{})
+
+	return nil
+}
+
+func validateClock(fl validator.FieldLevel) bool {
+	_, err := models.ParseClock(fl.Field().String())
+	return err == nil
+}
+
+// validateBcrypt rejects passwords bcrypt cannot hash. Its limit is 72
+// bytes, which max=72 alone does not enforce for non-ASCII characters.
+func validateBcrypt(fl validator.FieldLevel) bool {
+	return len(fl.Field().String()) <= 72
+}
+
+func validateAvailabilityWindow(sl validator.StructLevel) {
+	window := sl.Current().Interface().(availabilityWindowInput)
+
+	start, err := models.ParseClock(window.StartTime)
+	if err != nil {
+		return
+	}
+	end, err := models.ParseClock(window.EndTime)
+	if err != nil {
+		return
+	}
+
+	if end <= start {
+		sl.ReportError(window.EndTime, "EndTime", "EndTime", "after_start_time", "")
+	}
+}
+
+func validateAvailability(sl validator.StructLevel) {
+	input := sl.Current().Interface().(availabilityInput)
+
+	type span struct {
+		index      int
+		day        int
+		start, end int
+	}
+
+	spans := make([]span, 0, len(input.Windows))
+	for i, window := range input.Windows {
+		start, err := models.ParseClock(window.StartTime)
+		if err != nil || window.DayOfWeek == nil {
+			return
+		}
+		end, err := models.ParseClock(window.EndTime)
+		if err != nil {
+			return
+		}
+		spans = append(spans, span{index: i, day: *window.DayOfWeek, start: start, end: end})
+	}
+
+	slices.SortFunc(spans, func(a, b span) int {
+		if a.day != b.day {
+			return a.day - b.day
+		}
+		return a.start - b.start
+	})
+
+	for i := 1; i < len(spans); i++ {
+		if spans[i].day == spans[i-1].day && spans[i].start < spans[i-1].end {
+			field := fmt.Sprintf("Windows[%d]", spans[i].index)
+			sl.ReportError(input.Windows[spans[i].index], field, "Windows", "no_overlap", "")
+		}
+	}
+}
+
+func validateTimeOff(sl validator.StructLevel) {
+	input := sl.Current().Interface().(timeOffInput)
+
+	// Both dates are YYYY-MM-DD once the field rules pass, so they compare as strings
+	if len(input.StartDate) == len(time.DateOnly) && len(input.EndDate) == len(time.DateOnly) && input.EndDate < input.StartDate {
+		sl.ReportError(input.EndDate, "EndDate", "EndDate", "after_start_date", "")
+	}
+}
+
+func validateUserList(sl validator.StructLevel) {
+	input := sl.Current().Interface().(userListInput)
+
+	if limit, err := strconv.Atoi(input.Limit); err != nil || limit < 1 || limit > maxUserPageSize {
+		sl.ReportError(input.Limit, "Limit", "Limit", "page_size", strconv.Itoa(maxUserPageSize))
+	}
+
+	if input.CreatedAfter != "" && input.CreatedBefore != "" {
+		after, errAfter := time.Parse(time.RFC3339, input.CreatedAfter)
+		before, errBefore := time.Parse(time.RFC3339, input.CreatedBefore)
+		if errAfter == nil && errBefore == nil && !before.After(after) {
+			sl.ReportError(input.CreatedBefore, "CreatedBefore", "CreatedBefore", "after_created_after", "")
+		}
+	}
+
+	// A cursor only continues the sort order it was issued for, and its value
+	// must be one of that column
+	if input.Cursor != "" {
+		cursor, err := models.DecodeUserCursor(input.Cursor)
+		if err != nil || cursor.Sort != input.Sort || !validUserCursorValue(cursor) {
+			sl.ReportError(input.Cursor, "Cursor", "Cursor", "cursor", "")
+		}
+	}
+}
+
+func validUserCursorValue(cursor *models.UserCursor) bool {
+	switch strings.TrimPrefix(cursor.Sort, "-") {
+	case "created_at":
+		_, err := time.Parse(time.RFC3339Nano, cursor.Value)
+		return err == nil
+	default:
+		// Compared against a text column, which holds no invalid UTF-8 or NUL
+		return utf8.ValidString(cursor.Value) && !strings.ContainsRune(cursor.Value, 0)
+	}
+}
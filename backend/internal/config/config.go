@@ -2,7 +2,9 @@ package config
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/caarlos0/env/v11"
 )
@@ -27,6 +29,65 @@ func (e *Environment) UnmarshalText(text []byte) error {
 	return nil
 }
 
+// Backend selects where shared state such as login failures is kept.
+type Backend string
+
+const (
+	MemoryBackend Backend = "memory"
+	RedisBackend  Backend = "redis"
+)
+
+func (b *Backend) UnmarshalText(text []byte) error {
+	s := strings.ToLower(string(text))
+	switch s {
+	case "memory":
+		*b = MemoryBackend
+	case "redis":
+		*b = RedisBackend
+	default:
+		return fmt.Errorf("invalid backend value: %q, must be one of 'memory' or 'redis'", s)
+	}
+	return nil
+}
+
+// RateLimit allows Requests per Period and is written as "requests/period",
+// such as "100/1m". "off" disables the limit.
+type RateLimit struct {
+	Requests int
+	Period   time.Duration
+}
+
+func (l *RateLimit) UnmarshalText(text []byte) error {
+	s := strings.TrimSpace(string(text))
+	if strings.EqualFold(s, "off") {
+		*l = RateLimit{}
+		return nil
+	}
+
+	requests, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return fmt.Errorf("invalid rate limit value: %q, must look like '100/1m' or be 'off'", s)
+	}
+
+	n, err := strconv.Atoi(requests)
+	if err != nil || n <= 0 {
+		return fmt.Errorf("invalid rate limit requests: %q, must be a positive number", requests)
+	}
+
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return fmt.Errorf("invalid rate limit period: %q, must be a positive duration", period)
+	}
+
+	*l = RateLimit{Requests: n, Period: d}
+	return nil
+}
+
+// Enabled reports whether the limit applies at all.
+func (l RateLimit) Enabled() bool {
+	return l.Requests > 0
+}
+
 type Config struct {
 	Environment  Environment        `env:"ENVIRONMENT"`
 	Server       ServerConfig       `envPrefix:"SERVER_"`
@@ -35,6 +96,12 @@ type Config struct {
 	JWT          JWTConfig          `envPrefix:"JWT_"`
 	Redis        RedisConfig        `envPrefix:"REDIS_"`
 	SMTP         SMTPConfig         `envPrefix:"SMTP_"`
+	Swap         SwapConfig         `envPrefix:"SWAP_"`
+	User         UserConfig         `envPrefix:"USER_"`
+	Frontend     FrontendConfig     `envPrefix:"FRONTEND_"`
+	TOTP         TOTPConfig         `envPrefix:"TOTP_"`
+	Lockout      LockoutConfig      `envPrefix:"LOCKOUT_"`
+	RateLimit    RateLimitConfig    `envPrefix:"RATE_LIMIT_"`
 }
 
 type ServerConfig struct {
@@ -42,6 +109,12 @@ type ServerConfig struct {
 	ReadTimeout     int `env:"READ_TIMEOUT"`
 	WriteTimeout    int `env:"WRITE_TIMEOUT"`
 	ShutdownTimeout int `env:"SHUTDOWN_TIMEOUT"`
+	// PublicURL is the address clients reach the API on, used for links that
+	// point straight at the API such as calendar subscriptions.
+	PublicURL string `env:"PUBLIC_URL" envDefault:"http://localhost:3000"`
+	// TrustProxyHeaders takes the client IP from X-Forwarded-For or X-Real-IP.
+	// Only enable it behind a proxy that sets these headers itself.
+	TrustProxyHeaders bool `env:"TRUST_PROXY_HEADERS" envDefault:"false"`
 }
 
 type DatabaseConfig struct {
@@ -61,11 +134,18 @@ type InitialAdminConfig struct {
 	Username string `env:"USERNAME"`
 	Password string `env:"PASSWORD"`
 	Email    string `env:"EMAIL"`
+	// Organization names the host organization created with the initial
+	// admin on a fresh database.
+	Organization string `env:"ORGANIZATION" envDefault:"Default"`
 }
 
 type JWTConfig struct {
-	Secret     string `env:"SECRET"`
-	Expiration int    `env:"EXPIRATION"`
+	Secret string `env:"SECRET"`
+	// Expiration is the lifetime of an access token in seconds.
+	Expiration int `env:"EXPIRATION"`
+	// RefreshExpiration is the lifetime of a refresh token in seconds. Each
+	// refresh issues a new token, so a session lasts while it is in use.
+	RefreshExpiration int `env:"REFRESH_EXPIRATION" envDefault:"1209600"`
 }
 
 type RedisConfig struct {
@@ -84,6 +164,66 @@ type SMTPConfig struct {
 	Timeout  int    `env:"TIMEOUT"`
 }
 
+type SwapConfig struct {
+	AutoApprove bool `env:"AUTO_APPROVE" envDefault:"false"`
+}
+
+type UserConfig struct {
+	// PurgeGracePeriod is how long in seconds an archived user has to stay
+	// archived before they can be purged.
+	PurgeGracePeriod int `env:"PURGE_GRACE_PERIOD" envDefault:"2592000"`
+}
+
+type FrontendConfig struct {
+	// URL is the public address of the web app, used for links in emails.
+	URL string `env:"URL" envDefault:"http://localhost:5173"`
+}
+
+type TOTPConfig struct {
+	// Issuer is the account label shown in authenticator apps.
+	Issuer string `env:"ISSUER" envDefault:"WhenWorks"`
+	// RequireForAdmins keeps superadmins out of permission-guarded endpoints
+	// until they have enabled two-factor authentication.
+	RequireForAdmins bool `env:"REQUIRE_FOR_ADMINS" envDefault:"false"`
+}
+
+type LockoutConfig struct {
+	// Backend keeps failed login attempts in process or in Redis, which is
+	// needed to share them between several instances.
+	Backend Backend `env:"BACKEND" envDefault:"memory"`
+	// UsernameThreshold and IPThreshold are the failed logins that lock out
+	// a username or a client IP. The IP threshold is higher because many
+	// users can share an address.
+	UsernameThreshold int `env:"USERNAME_THRESHOLD" envDefault:"5"`
+	IPThreshold       int `env:"IP_THRESHOLD" envDefault:"20"`
+	// BaseDelay is the first lockout in seconds. It doubles with every
+	// further failure, up to MaxDelay.
+	BaseDelay int `env:"BASE_DELAY" envDefault:"30"`
+	MaxDelay  int `env:"MAX_DELAY" envDefault:"3600"`
+	// Window is how long in seconds failures are remembered.
+	Window int `env:"WINDOW" envDefault:"86400"`
+}
+
+// RateLimitConfig sets the request limit of each group of routes. Anonymous
+// routes are limited per client IP, authenticated ones per client IP and then
+// per user.
+type RateLimitConfig struct {
+	// Backend keeps the buckets in process or in Redis, which is needed to
+	// share them between several instances.
+	Backend Backend `env:"BACKEND" envDefault:"memory"`
+	// Auth covers the sign-in, refresh, activation and password reset routes.
+	Auth RateLimit `env:"AUTH" envDefault:"20/1m"`
+	// Public covers the token links: poll invites and calendar feeds.
+	Public RateLimit `env:"PUBLIC" envDefault:"60/1m"`
+	// Client covers every authenticated route per client IP, ahead of
+	// authentication, so requests with invalid credentials are limited too.
+	Client RateLimit `env:"CLIENT" envDefault:"1200/1m"`
+	// User covers the routes open to every signed-in user.
+	User RateLimit `env:"USER" envDefault:"300/1m"`
+	// Admin covers the admin routes.
+	Admin RateLimit `env:"ADMIN" envDefault:"600/1m"`
+}
+
 func LoadConfig() (Config, error) {
 	cfg := Config{}
 	if err := env.ParseWithOptions(&cfg, env.Options{RequiredIfNoDef: true}); err != nil {
@@ -0,0 +1,144 @@
+// Package ical writes iCalendar (RFC 5545) documents.
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxLineOctets is the longest content line allowed before folding,
+// excluding the line break.
+const maxLineOctets = 75
+
+const dateTimeFormat = "20060102T150405Z"
+
+type Calendar struct {
+	// ProdID identifies the product that created the calendar.
+	ProdID string
+	// Name is shown by clients that support X-WR-CALNAME.
+	Name string
+	// RefreshInterval hints how often subscribers should poll. Zero omits it.
+	RefreshInterval time.Duration
+	Events          []Event
+}
+
+// Event is a VEVENT. UID must stay the same across exports for clients to
+// update the event rather than add a copy.
+type Event struct {
+	UID          string
+	Summary      string
+	Description  string
+	Start        time.Time
+	End          time.Time
+	Stamp        time.Time
+	LastModified time.Time
+
+	// The fields below are filled in by Parse and ignored by Encode.
+
+	AllDay     bool
+	Recurrence *Recurrence
+	ExDates    []time.Time
+	// RecurrenceID is set on an event that overrides one instance of the
+	// recurring event with the same UID.
+	RecurrenceID *time.Time
+	Transparent  bool
+	Cancelled    bool
+}
+
+// Encode writes the calendar with CRLF line endings and folded lines.
+func Encode(w io.Writer, calendar *Calendar) error {
+	bw := bufio.NewWriter(w)
+
+	writeLine(bw, "BEGIN:VCALENDAR")
+	writeLine(bw, "VERSION:2.0")
+	writeLine(bw, "PRODID:"+calendar.ProdID)
+	writeLine(bw, "CALSCALE:GREGORIAN")
+	writeLine(bw, "METHOD:PUBLISH")
+	if calendar.Name != "" {
+		writeLine(bw, "X-WR-CALNAME:"+escapeText(calendar.Name))
+	}
+	if calendar.RefreshInterval > 0 {
+		duration := formatDuration(calendar.RefreshInterval)
+		writeLine(bw, "REFRESH-INTERVAL;VALUE=DURATION:"+duration)
+		writeLine(bw, "X-PUBLISHED-TTL:"+duration)
+	}
+
+	for _, event := range calendar.Events {
+		writeLine(bw, "BEGIN:VEVENT")
+		writeLine(bw, "UID:"+escapeText(event.UID))
+		writeLine(bw, "DTSTAMP:"+formatTime(event.Stamp))
+		writeLine(bw, "DTSTART:"+formatTime(event.Start))
+		writeLine(bw, "DTEND:"+formatTime(event.End))
+		writeLine(bw, "SUMMARY:"+escapeText(event.Summary))
+		if event.Description != "" {
+			writeLine(bw, "DESCRIPTION:"+escapeText(event.Description))
+		}
+		if !event.LastModified.IsZero() {
+			writeLine(bw, "LAST-MODIFIED:"+formatTime(event.LastModified))
+		}
+		writeLine(bw, "END:VEVENT")
+	}
+
+	writeLine(bw, "END:VCALENDAR")
+
+	return bw.Flush()
+}
+
+// writeLine folds the line into chunks of at most maxLineOctets octets,
+// never splitting a UTF-8 sequence. Continuation lines start with a space,
+// which counts towards their length.
+func writeLine(w *bufio.Writer, line string) {
+	limit := maxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		w.WriteString(line[:cut])
+		w.WriteString("\r\n ")
+		line = line[cut:]
+		limit = maxLineOctets - 1
+	}
+	w.WriteString(line)
+	w.WriteString("\r\n")
+}
+
+var textEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`;`, `\;`,
+	`,`, `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+	"\r", `\n`,
+)
+
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(dateTimeFormat)
+}
+
+// formatDuration renders whole seconds as an RFC 5545 duration such as PT1H.
+func formatDuration(d time.Duration) string {
+	seconds := int64(d / time.Second)
+
+	var b strings.Builder
+	b.WriteString("PT")
+	if h := seconds / 3600; h > 0 {
+		b.WriteString(strconv.FormatInt(h, 10) + "H")
+	}
+	if m := seconds % 3600 / 60; m > 0 {
+		b.WriteString(strconv.FormatInt(m, 10) + "M")
+	}
+	if s := seconds % 60; s > 0 || seconds == 0 {
+		b.WriteString(strconv.FormatInt(s, 10) + "S")
+	}
+
+	return b.String()
+}
@@ -0,0 +1,390 @@
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseError reports why a document could not be parsed. Line is the
+// physical line, counted from 1, on which the offending content line starts.
+type ParseError struct {
+	Line   int
+	Reason string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Reason)
+}
+
+// maxContentLine bounds a single unfolded content line.
+const maxContentLine = 1 << 20
+
+type contentLine struct {
+	line   int
+	name   string
+	params map[string]string
+	value  string
+}
+
+// Parse reads the VEVENTs of an iCalendar document. Floating times, dates and
+// times in a TZID the system does not know are read in loc. Other components
+// such as VTIMEZONE and VALARM are skipped.
+func Parse(r io.Reader, loc *time.Location) (*Calendar, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	calendar := &Calendar{}
+	var stack []string
+	var event *Event
+	var hasStart, hasEnd bool
+	var duration time.Duration
+	var hasDuration bool
+	lastLine := 0
+
+	for _, cl := range lines {
+		lastLine = cl.line
+
+		switch cl.name {
+		case "BEGIN":
+			component := strings.ToUpper(cl.value)
+			if len(stack) == 0 && component != "VCALENDAR" {
+				return nil, &ParseError{Line: cl.line, Reason: "expected BEGIN:VCALENDAR"}
+			}
+			stack = append(stack, component)
+			if component == "VEVENT" && len(stack) == 2 {
+				event = &Event{}
+				hasStart, hasEnd, hasDuration, duration = false, false, false, 0
+			}
+			continue
+
+		case "END":
+			component := strings.ToUpper(cl.value)
+			if len(stack) == 0 || stack[len(stack)-1] != component {
+				return nil, &ParseError{Line: cl.line, Reason: "unexpected END:" + cl.value}
+			}
+			stack = stack[:len(stack)-1]
+			if component == "VEVENT" && event != nil && len(stack) == 1 {
+				if !hasStart {
+					return nil, &ParseError{Line: cl.line, Reason: "VEVENT has no DTSTART"}
+				}
+				switch {
+				case hasEnd:
+				case hasDuration:
+					event.End = event.Start.Add(duration)
+				case event.AllDay:
+					event.End = event.Start.AddDate(0, 0, 1)
+				default:
+					event.End = event.Start
+				}
+				if event.End.Before(event.Start) {
+					return nil, &ParseError{Line: cl.line, Reason: "VEVENT ends before it starts"}
+				}
+				calendar.Events = append(calendar.Events, *event)
+				event = nil
+			}
+			continue
+		}
+
+		if len(stack) == 0 {
+			return nil, &ParseError{Line: cl.line, Reason: "expected BEGIN:VCALENDAR"}
+		}
+
+		// Only properties that belong directly to a VEVENT matter
+		if event == nil || len(stack) != 2 {
+			continue
+		}
+
+		switch cl.name {
+		case "UID":
+			event.UID = cl.value
+		case "SUMMARY":
+			event.Summary = unescapeText(cl.value)
+		case "DESCRIPTION":
+			event.Description = unescapeText(cl.value)
+		case "DTSTART":
+			t, allDay, err := parseDateTime(cl.value, cl.params, loc)
+			if err != nil {
+				return nil, &ParseError{Line: cl.line, Reason: "invalid DTSTART: " + err.Error()}
+			}
+			event.Start, event.AllDay, hasStart = t, allDay, true
+		case "DTEND":
+			t, _, err := parseDateTime(cl.value, cl.params, loc)
+			if err != nil {
+				return nil, &ParseError{Line: cl.line, Reason: "invalid DTEND: " + err.Error()}
+			}
+			event.End, hasEnd = t, true
+		case "DURATION":
+			d, err := parseDuration(cl.value)
+			if err != nil {
+				return nil, &ParseError{Line: cl.line, Reason: "invalid DURATION: " + err.Error()}
+			}
+			duration, hasDuration = d, true
+		case "RRULE":
+			if event.Recurrence != nil {
+				return nil, &ParseError{Line: cl.line, Reason: "multiple RRULEs are not supported"}
+			}
+			rule, err := parseRecurrence(cl.value, loc)
+			if err != nil {
+				return nil, &ParseError{Line: cl.line, Reason: "invalid RRULE: " + err.Error()}
+			}
+			event.Recurrence = rule
+		case "EXDATE":
+			for value := range strings.SplitSeq(cl.value, ",") {
+				t, _, err := parseDateTime(value, cl.params, loc)
+				if err != nil {
+					return nil, &ParseError{Line: cl.line, Reason: "invalid EXDATE: " + err.Error()}
+				}
+				event.ExDates = append(event.ExDates, t)
+			}
+		case "RECURRENCE-ID":
+			t, _, err := parseDateTime(cl.value, cl.params, loc)
+			if err != nil {
+				return nil, &ParseError{Line: cl.line, Reason: "invalid RECURRENCE-ID: " + err.Error()}
+			}
+			event.RecurrenceID = &t
+		case "TRANSP":
+			event.Transparent = strings.EqualFold(cl.value, "TRANSPARENT")
+		case "STATUS":
+			event.Cancelled = strings.EqualFold(cl.value, "CANCELLED")
+		}
+	}
+
+	if len(stack) != 0 {
+		return nil, &ParseError{Line: lastLine, Reason: "missing END:" + stack[len(stack)-1]}
+	}
+	if lastLine == 0 {
+		return nil, &ParseError{Line: 1, Reason: "expected BEGIN:VCALENDAR"}
+	}
+
+	return calendar, nil
+}
+
+// unfold joins folded lines and splits each content line into its parts.
+func unfold(r io.Reader) ([]contentLine, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxContentLine)
+
+	var lines []contentLine
+	var current strings.Builder
+	start, number := 0, 0
+
+	flush := func() error {
+		if current.Len() == 0 {
+			return nil
+		}
+		cl, err := splitContentLine(current.String())
+		if err != nil {
+			return &ParseError{Line: start, Reason: err.Error()}
+		}
+		cl.line = start
+		lines = append(lines, cl)
+		current.Reset()
+		return nil
+	}
+
+	for scanner.Scan() {
+		number++
+		text := strings.TrimSuffix(scanner.Text(), "\r")
+
+		if strings.HasPrefix(text, " ") || strings.HasPrefix(text, "\t") {
+			if current.Len() == 0 {
+				return nil, &ParseError{Line: number, Reason: "continuation line without a content line"}
+			}
+			if current.Len()+len(text) > maxContentLine {
+				return nil, &ParseError{Line: start, Reason: "content line is too long"}
+			}
+			current.WriteString(text[1:])
+			continue
+		}
+
+		if err := flush(); err != nil {
+			return nil, err
+		}
+
+		if text == "" {
+			continue
+		}
+
+		current.WriteString(text)
+		start = number
+	}
+
+	if err := scanner.Err(); err != nil {
+		if err == bufio.ErrTooLong {
+			return nil, &ParseError{Line: number + 1, Reason: "content line is too long"}
+		}
+		return nil, err
+	}
+
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	return lines, nil
+}
+
+// splitContentLine parses "NAME;PARAM=VALUE:value". Parameter values may be
+// quoted, in which case ';' and ':' inside them are literal.
+func splitContentLine(text string) (contentLine, error) {
+	cl := contentLine{params: map[string]string{}}
+
+	i := strings.IndexAny(text, ";:")
+	if i <= 0 {
+		return cl, fmt.Errorf("expected NAME:value")
+	}
+	cl.name = strings.ToUpper(text[:i])
+
+	for text[i] == ';' {
+		rest := text[i+1:]
+
+		eq := strings.IndexByte(rest, '=')
+		if eq <= 0 {
+			return cl, fmt.Errorf("invalid parameter in %s", cl.name)
+		}
+		key := strings.ToUpper(rest[:eq])
+
+		j := eq + 1
+		var value string
+		if j < len(rest) && rest[j] == '"' {
+			end := strings.IndexByte(rest[j+1:], '"')
+			if end < 0 {
+				return cl, fmt.Errorf("unterminated quote in %s", cl.name)
+			}
+			value = rest[j+1 : j+1+end]
+			j += end + 2
+		} else {
+			end := strings.IndexAny(rest[j:], ";:")
+			if end < 0 {
+				return cl, fmt.Errorf("expected ':' after parameters of %s", cl.name)
+			}
+			value = rest[j : j+end]
+			j += end
+		}
+
+		if j >= len(rest) || (rest[j] != ';' && rest[j] != ':') {
+			return cl, fmt.Errorf("expected ':' after parameters of %s", cl.name)
+		}
+
+		cl.params[key] = value
+		i += 1 + j
+	}
+
+	cl.value = text[i+1:]
+	return cl, nil
+}
+
+var textUnescaper = strings.NewReplacer(
+	`\\`, `\`,
+	`\;`, `;`,
+	`\,`, `,`,
+	`\n`, "\n",
+	`\N`, "\n",
+)
+
+func unescapeText(s string) string {
+	return textUnescaper.Replace(s)
+}
+
+// parseDateTime reads a DATE or DATE-TIME value. The second result reports
+// whether the value was a DATE.
+func parseDateTime(value string, params map[string]string, loc *time.Location) (time.Time, bool, error) {
+	value = strings.TrimSpace(value)
+
+	if strings.EqualFold(params["VALUE"], "DATE") || len(value) == len("20060102") {
+		t, err := time.ParseInLocation("20060102", value, loc)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("expected YYYYMMDD, got %q", value)
+		}
+		return t, true, nil
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse(dateTimeFormat, value)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("expected YYYYMMDDTHHMMSSZ, got %q", value)
+		}
+		return t, false, nil
+	}
+
+	if tzid := strings.TrimPrefix(params["TZID"], "/"); tzid != "" {
+		// Names such as Windows zone IDs are not in the tz database; fall
+		// back to loc rather than rejecting the whole file
+		if zone, err := time.LoadLocation(tzid); err == nil {
+			loc = zone
+		}
+	}
+
+	t, err := time.ParseInLocation("20060102T150405", value, loc)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("expected YYYYMMDDTHHMMSS, got %q", value)
+	}
+	return t, false, nil
+}
+
+// parseDuration reads an RFC 5545 duration such as P1D, PT1H30M or -P1W.
+func parseDuration(value string) (time.Duration, error) {
+	s := strings.ToUpper(strings.TrimSpace(value))
+
+	sign := time.Duration(1)
+	switch {
+	case strings.HasPrefix(s, "-"):
+		sign, s = -1, s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+
+	if !strings.HasPrefix(s, "P") || len(s) < 3 {
+		return 0, fmt.Errorf("expected a duration such as PT1H, got %q", value)
+	}
+	s = s[1:]
+
+	var total time.Duration
+	inTime := false
+	number := ""
+
+	for _, c := range s {
+		switch {
+		case c >= '0' && c <= '9':
+			number += string(c)
+			continue
+		case c == 'T' && !inTime && number == "":
+			inTime = true
+			continue
+		}
+
+		n, err := strconv.Atoi(number)
+		if err != nil {
+			return 0, fmt.Errorf("expected a duration such as PT1H, got %q", value)
+		}
+		number = ""
+
+		var unit time.Duration
+		switch {
+		case !inTime && c == 'W':
+			unit = 7 * 24 * time.Hour
+		case !inTime && c == 'D':
+			unit = 24 * time.Hour
+		case inTime && c == 'H':
+			unit = time.Hour
+		case inTime && c == 'M':
+			unit = time.Minute
+		case inTime && c == 'S':
+			unit = time.Second
+		default:
+			return 0, fmt.Errorf("expected a duration such as PT1H, got %q", value)
+		}
+		total += time.Duration(n) * unit
+	}
+
+	if number != "" {
+		return 0, fmt.Errorf("expected a duration such as PT1H, got %q", value)
+	}
+
+	return sign * total, nil
+}
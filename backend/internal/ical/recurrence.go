@@ -0,0 +1,400 @@
+package ical
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrTooManyOccurrences = errors.New("too many occurrences")
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+// WeekdayNum is a BYDAY entry such as MO, 2TU or -1FR. Ordinal 0 means every
+// such weekday in the period.
+type WeekdayNum struct {
+	Ordinal int
+	Weekday time.Weekday
+}
+
+// Recurrence is the supported subset of an RRULE: FREQ from DAILY to YEARLY
+// with INTERVAL, COUNT or UNTIL, BYDAY, BYMONTHDAY, BYMONTH and WKST.
+type Recurrence struct {
+	Frequency  Frequency
+	Interval   int
+	Count      int
+	Until      time.Time
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []time.Month
+	WeekStart  time.Weekday
+}
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+func parseRecurrence(value string, loc *time.Location) (*Recurrence, error) {
+	rule := &Recurrence{Interval: 1, WeekStart: time.Monday}
+
+	for part := range strings.SplitSeq(value, ";") {
+		key, val, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("expected KEY=value, got %q", part)
+		}
+		key = strings.ToUpper(key)
+
+		switch key {
+		case "FREQ":
+			switch f := Frequency(strings.ToUpper(val)); f {
+			case Daily, Weekly, Monthly, Yearly:
+				rule.Frequency = f
+			default:
+				return nil, fmt.Errorf("unsupported FREQ %q", val)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("INTERVAL must be a positive integer")
+			}
+			rule.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("COUNT must be a positive integer")
+			}
+			rule.Count = n
+		case "UNTIL":
+			t, _, err := parseDateTime(val, nil, loc)
+			if err != nil {
+				return nil, fmt.Errorf("invalid UNTIL: %w", err)
+			}
+			rule.Until = t
+		case "BYDAY":
+			for entry := range strings.SplitSeq(val, ",") {
+				entry = strings.ToUpper(entry)
+				if len(entry) < 2 {
+					return nil, fmt.Errorf("invalid BYDAY %q", entry)
+				}
+				weekday, ok := weekdays[entry[len(entry)-2:]]
+				if !ok {
+					return nil, fmt.Errorf("invalid BYDAY %q", entry)
+				}
+				ordinal := 0
+				if prefix := entry[:len(entry)-2]; prefix != "" {
+					n, err := strconv.Atoi(prefix)
+					if err != nil || n == 0 || n < -5 || n > 5 {
+						return nil, fmt.Errorf("invalid BYDAY %q", entry)
+					}
+					ordinal = n
+				}
+				rule.ByDay = append(rule.ByDay, WeekdayNum{Ordinal: ordinal, Weekday: weekday})
+			}
+		case "BYMONTHDAY":
+			for entry := range strings.SplitSeq(val, ",") {
+				n, err := strconv.Atoi(entry)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return nil, fmt.Errorf("invalid BYMONTHDAY %q", entry)
+				}
+				rule.ByMonthDay = append(rule.ByMonthDay, n)
+			}
+		case "BYMONTH":
+			for entry := range strings.SplitSeq(val, ",") {
+				n, err := strconv.Atoi(entry)
+				if err != nil || n < 1 || n > 12 {
+					return nil, fmt.Errorf("invalid BYMONTH %q", entry)
+				}
+				rule.ByMonth = append(rule.ByMonth, time.Month(n))
+			}
+		case "WKST":
+			weekday, ok := weekdays[strings.ToUpper(val)]
+			if !ok {
+				return nil, fmt.Errorf("invalid WKST %q", val)
+			}
+			rule.WeekStart = weekday
+		default:
+			return nil, fmt.Errorf("unsupported part %s", key)
+		}
+	}
+
+	if rule.Frequency == "" {
+		return nil, errors.New("FREQ is required")
+	}
+	if rule.Count > 0 && !rule.Until.IsZero() {
+		return nil, errors.New("COUNT and UNTIL cannot both be set")
+	}
+	for _, day := range rule.ByDay {
+		if day.Ordinal != 0 && rule.Frequency != Monthly && rule.Frequency != Yearly {
+			return nil, errors.New("BYDAY ordinals are only supported with MONTHLY or YEARLY")
+		}
+	}
+	if rule.Frequency == Yearly && len(rule.ByDay) > 0 && len(rule.ByMonth) == 0 {
+		return nil, errors.New("YEARLY with BYDAY requires BYMONTH")
+	}
+
+	return rule, nil
+}
+
+// Occurrence is one instance of an event.
+type Occurrence struct {
+	Event *Event
+	Start time.Time
+	End   time.Time
+}
+
+// maxPeriods stops rules whose filters never match, such as the 30th of
+// February, from looping forever.
+const maxPeriods = 100_000
+
+// Expand returns the occurrences of the events that overlap [from, to),
+// applying EXDATEs and instances overridden through RECURRENCE-ID. It fails
+// with ErrTooManyOccurrences once more than limit occurrences are found.
+func Expand(events []Event, from, to time.Time, limit int) ([]Occurrence, error) {
+	overridden := make(map[string][]time.Time)
+	for _, event := range events {
+		if event.RecurrenceID != nil {
+			overridden[event.UID] = append(overridden[event.UID], *event.RecurrenceID)
+		}
+	}
+
+	var occurrences []Occurrence
+	emit := func(event *Event, start time.Time) error {
+		end := start.Add(event.End.Sub(event.Start))
+		if !start.Before(to) || !end.After(from) {
+			return nil
+		}
+		if len(occurrences) >= limit {
+			return ErrTooManyOccurrences
+		}
+		occurrences = append(occurrences, Occurrence{Event: event, Start: start, End: end})
+		return nil
+	}
+
+	for i := range events {
+		event := &events[i]
+
+		if event.Recurrence == nil || event.RecurrenceID != nil {
+			if err := emit(event, event.Start); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		excluded := append(slices.Clone(event.ExDates), overridden[event.UID]...)
+		err := event.Recurrence.each(event.Start, to, func(start time.Time) error {
+			for _, exdate := range excluded {
+				if exdate.Equal(start) {
+					return nil
+				}
+			}
+			return emit(event, start)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	slices.SortFunc(occurrences, func(a, b Occurrence) int {
+		return a.Start.Compare(b.Start)
+	})
+
+	return occurrences, nil
+}
+
+// each calls fn for every instance starting at dtstart, in order, until the
+// rule ends or an instance starts at or after to. DTSTART is always the first
+// instance.
+func (rule *Recurrence) each(dtstart, to time.Time, fn func(time.Time) error) error {
+	loc := dtstart.Location()
+	hour, minute, second := dtstart.Clock()
+
+	count := 0
+	yield := func(t time.Time) (bool, error) {
+		if !rule.Until.IsZero() && t.After(rule.Until) {
+			return false, nil
+		}
+		if rule.Count > 0 && count >= rule.Count {
+			return false, nil
+		}
+		if !t.Before(to) {
+			return false, nil
+		}
+		count++
+		return true, fn(t)
+	}
+
+	if more, err := yield(dtstart); !more || err != nil {
+		return err
+	}
+
+	for period := 0; period < maxPeriods; period++ {
+		days, periodStart := rule.period(dtstart, period)
+		if !periodStart.Before(to) {
+			return nil
+		}
+
+		for _, day := range days {
+			t := time.Date(day.Year(), day.Month(), day.Day(), hour, minute, second, 0, loc)
+			if !t.After(dtstart) {
+				continue
+			}
+			if more, err := yield(t); !more || err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// period returns the sorted candidate days of the n-th period and the start
+// of that period. Days are midnight UTC values used only for their date.
+func (rule *Recurrence) period(dtstart time.Time, n int) ([]time.Time, time.Time) {
+	first := time.Date(dtstart.Year(), dtstart.Month(), dtstart.Day(), 0, 0, 0, 0, time.UTC)
+	step := n * rule.Interval
+
+	var days []time.Time
+	var start time.Time
+
+	switch rule.Frequency {
+	case Daily:
+		start = first.AddDate(0, 0, step)
+		if rule.matchesDay(start) {
+			days = append(days, start)
+		}
+
+	case Weekly:
+		offset := (int(first.Weekday()) - int(rule.WeekStart) + 7) % 7
+		start = first.AddDate(0, 0, 7*step-offset)
+		weekdays := []time.Weekday{first.Weekday()}
+		if len(rule.ByDay) > 0 {
+			weekdays = weekdays[:0]
+			for _, day := range rule.ByDay {
+				weekdays = append(weekdays, day.Weekday)
+			}
+		}
+		for _, weekday := range weekdays {
+			day := start.AddDate(0, 0, (int(weekday)-int(rule.WeekStart)+7)%7)
+			if rule.matchesMonth(day) {
+				days = append(days, day)
+			}
+		}
+
+	case Monthly:
+		start = time.Date(first.Year(), first.Month()+time.Month(step), 1, 0, 0, 0, 0, time.UTC)
+		if rule.matchesMonth(start) {
+			days = rule.monthDays(start, first.Day())
+		}
+
+	case Yearly:
+		start = time.Date(first.Year()+step, time.January, 1, 0, 0, 0, 0, time.UTC)
+		months := rule.ByMonth
+		if len(months) == 0 {
+			months = []time.Month{first.Month()}
+		}
+		for _, month := range months {
+			days = append(days, rule.monthDays(time.Date(start.Year(), month, 1, 0, 0, 0, 0, time.UTC), first.Day())...)
+		}
+	}
+
+	slices.SortFunc(days, func(a, b time.Time) int { return a.Compare(b) })
+	days = slices.CompactFunc(days, func(a, b time.Time) bool { return a.Equal(b) })
+
+	return days, time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, dtstart.Location())
+}
+
+// monthDays expands BYMONTHDAY and BYDAY within the month starting at
+// month. Without either, the day of DTSTART is used if the month has it.
+func (rule *Recurrence) monthDays(month time.Time, defaultDay int) []time.Time {
+	length := month.AddDate(0, 1, -1).Day()
+
+	var days []time.Time
+	add := func(day int) {
+		if day >= 1 && day <= length {
+			days = append(days, month.AddDate(0, 0, day-1))
+		}
+	}
+
+	switch {
+	case len(rule.ByMonthDay) > 0:
+		for _, day := range rule.ByMonthDay {
+			if day < 0 {
+				day = length + day + 1
+			}
+			if len(rule.ByDay) > 0 && !rule.matchesWeekday(month.AddDate(0, 0, day-1)) {
+				continue
+			}
+			add(day)
+		}
+
+	case len(rule.ByDay) > 0:
+		for _, entry := range rule.ByDay {
+			firstMatch := 1 + (int(entry.Weekday)-int(month.Weekday())+7)%7
+			switch {
+			case entry.Ordinal > 0:
+				add(firstMatch + 7*(entry.Ordinal-1))
+			case entry.Ordinal < 0:
+				last := firstMatch + 7*((length-firstMatch)/7)
+				add(last + 7*(entry.Ordinal+1))
+			default:
+				for day := firstMatch; day <= length; day += 7 {
+					add(day)
+				}
+			}
+		}
+
+	default:
+		add(defaultDay)
+	}
+
+	return days
+}
+
+func (rule *Recurrence) matchesDay(day time.Time) bool {
+	if !rule.matchesMonth(day) {
+		return false
+	}
+	if len(rule.ByMonthDay) > 0 {
+		length := day.AddDate(0, 1, -day.Day()).Day()
+		matched := false
+		for _, n := range rule.ByMonthDay {
+			if n == day.Day() || length+n+1 == day.Day() {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return len(rule.ByDay) == 0 || rule.matchesWeekday(day)
+}
+
+func (rule *Recurrence) matchesWeekday(day time.Time) bool {
+	for _, entry := range rule.ByDay {
+		if entry.Weekday == day.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+func (rule *Recurrence) matchesMonth(day time.Time) bool {
+	return len(rule.ByMonth) == 0 || slices.Contains(rule.ByMonth, day.Month())
+}
@@ -0,0 +1,96 @@
+// Package lockout tracks failed attempts per key, such as a username or a
+// client IP, and locks a key out for exponentially longer periods once it
+// has failed too often.
+package lockout
+
+import (
+	"context"
+	"time"
+)
+
+// Store keeps failure counts and lockouts. Implementations must be safe for
+// concurrent use; counting has to be atomic so parallel guesses cannot slip
+// past the threshold.
+type Store interface {
+	// Fail records a failed attempt and returns the number of failures since
+	// the last reset. The count is forgotten after ttl without a new failure.
+	Fail(ctx context.Context, key string, ttl time.Duration) (int, error)
+	// Lock blocks the key until the given time.
+	Lock(ctx context.Context, key string, until time.Time) error
+	// LockedUntil returns the time the key is blocked until, or the zero time
+	// if it is not blocked.
+	LockedUntil(ctx context.Context, key string) (time.Time, error)
+	// Reset forgets the failures and any lockout of the key.
+	Reset(ctx context.Context, key string) error
+}
+
+// Policy decides when and for how long a key is locked out.
+type Policy struct {
+	// Threshold is the number of failures that triggers the first lockout.
+	Threshold int
+	// BaseDelay is the length of the first lockout. Every failure after it
+	// doubles the lockout, up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Window is how long failures are remembered without a new one.
+	Window time.Duration
+}
+
+// Delay returns the lockout that follows the given number of failures.
+func (p Policy) Delay(failures int) time.Duration {
+	if p.Threshold <= 0 || failures < p.Threshold {
+		return 0
+	}
+
+	delay := p.BaseDelay
+	for i := p.Threshold; i < failures && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, p.MaxDelay)
+}
+
+// Limiter applies a policy to keys of one kind. Its prefix keeps the keys of
+// different limiters sharing a store apart.
+type Limiter struct {
+	store  Store
+	prefix string
+	policy Policy
+}
+
+func NewLimiter(store Store, prefix string, policy Policy) *Limiter {
+	return &Limiter{store: store, prefix: prefix + ":", policy: policy}
+}
+
+// Check returns how long the key remains locked out, zero if it is not.
+func (l *Limiter) Check(ctx context.Context, key string) (time.Duration, error) {
+	until, err := l.store.LockedUntil(ctx, l.prefix+key)
+	if err != nil {
+		return 0, err
+	}
+
+	return max(time.Until(until), 0), nil
+}
+
+// Fail records a failure of the key and returns the lockout it triggered, if
+// any.
+func (l *Limiter) Fail(ctx context.Context, key string) (time.Duration, error) {
+	failures, err := l.store.Fail(ctx, l.prefix+key, l.policy.Window)
+	if err != nil {
+		return 0, err
+	}
+
+	delay := l.policy.Delay(failures)
+	if delay > 0 {
+		if err := l.store.Lock(ctx, l.prefix+key, time.Now().Add(delay)); err != nil {
+			return 0, err
+		}
+	}
+
+	return delay, nil
+}
+
+// Reset clears the failures and any lockout of the key.
+func (l *Limiter) Reset(ctx context.Context, key string) error {
+	return l.store.Reset(ctx, l.prefix+key)
+}
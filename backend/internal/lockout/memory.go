@@ -0,0 +1,95 @@
+package lockout
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const sweepInterval = time.Minute
+
+type memoryEntry struct {
+	failures    int
+	expires     time.Time
+	lockedUntil time.Time
+}
+
+func (e *memoryEntry) expired(now time.Time) bool {
+	return now.After(e.expires) && now.After(e.lockedUntil)
+}
+
+// MemoryStore keeps the state in process. It suits a single instance; with
+// several, each would count failures on its own.
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]*memoryEntry
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]*memoryEntry)}
+}
+
+// entry returns the live entry of the key, creating it if needed. Callers
+// hold the lock.
+func (s *MemoryStore) entry(key string, now time.Time) *memoryEntry {
+	if now.Sub(s.lastSweep) > sweepInterval {
+		for k, e := range s.entries {
+			if e.expired(now) {
+				delete(s.entries, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	e, ok := s.entries[key]
+	if !ok || e.expired(now) {
+		e = &memoryEntry{}
+		s.entries[key] = e
+	}
+
+	return e
+}
+
+func (s *MemoryStore) Fail(_ context.Context, key string, ttl time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	e := s.entry(key, now)
+	if now.After(e.expires) {
+		e.failures = 0
+	}
+	e.failures++
+	e.expires = now.Add(ttl)
+
+	return e.failures, nil
+}
+
+func (s *MemoryStore) Lock(_ context.Context, key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entry(key, time.Now()).lockedUntil = until
+	return nil
+}
+
+func (s *MemoryStore) LockedUntil(_ context.Context, key string) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok || e.expired(time.Now()) {
+		return time.Time{}, nil
+	}
+
+	return e.lockedUntil, nil
+}
+
+func (s *MemoryStore) Reset(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
@@ -0,0 +1,72 @@
+package lockout
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStore keeps the state in Redis so every instance sees the same
+// failures and lockouts.
+type RedisStore struct {
+	client *redis.Client
+}
+
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+func failuresKey(key string) string {
+	return "lockout:failures:" + key
+}
+
+func lockKey(key string) string {
+	return "lockout:lock:" + key
+}
+
+func (s *RedisStore) Fail(ctx context.Context, key string, ttl time.Duration) (int, error) {
+	var incr *redis.IntCmd
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(ctx, failuresKey(key))
+		pipe.PExpire(ctx, failuresKey(key), ttl)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return int(incr.Val()), nil
+}
+
+func (s *RedisStore) Lock(ctx context.Context, key string, until time.Time) error {
+	ttl := time.Until(until)
+	if ttl <= 0 {
+		return nil
+	}
+
+	return s.client.Set(ctx, lockKey(key), until.UnixMilli(), ttl).Err()
+}
+
+func (s *RedisStore) LockedUntil(ctx context.Context, key string) (time.Time, error) {
+	value, err := s.client.Get(ctx, lockKey(key)).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+
+	ms, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.UnixMilli(ms), nil
+}
+
+func (s *RedisStore) Reset(ctx context.Context, key string) error {
+	return s.client.Del(ctx, failuresKey(key), lockKey(key)).Err()
+}
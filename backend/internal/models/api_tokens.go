@@ -0,0 +1,232 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jonathanhu237/when-works/backend/internal/config"
+)
+
+// Scopes of personal access tokens. Read-only tokens may make safe requests
+// to any route their user can reach; the others each cover a group of routes.
+const (
+	APIScopeRead          = "read"
+	APIScopeWrite         = "write"
+	APIScopeScheduleAdmin = "schedule-admin"
+	APIScopeAdmin         = "admin"
+)
+
+// apiTokenPrefix marks personal access tokens so they are easy to recognise,
+// for example by secret scanners.
+const apiTokenPrefix = "ww_pat_"
+
+// APIToken is a personal access token a user created for scripts. Only its
+// hash is stored; Prefix keeps the first characters so the user can tell
+// their tokens apart.
+type APIToken struct {
+	ID         uuid.UUID  `json:"id"`
+	UserID     uuid.UUID  `json:"-"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// HasScope reports whether the token carries any of the scopes.
+func (t *APIToken) HasScope(scopes ...string) bool {
+	for _, have := range t.Scopes {
+		for _, want := range scopes {
+			if have == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// scanTextArray scans a TEXT[] column, which database/sql cannot do by
+// itself. A pgtype.Map is not safe for concurrent use, so each scan gets its
+// own; they are cheap to create.
+func scanTextArray(dest *[]string) sql.Scanner {
+	return pgtype.NewMap().SQLScanner(dest)
+}
+
+type APITokenModel struct {
+	DB     *sql.DB
+	config config.Config
+}
+
+// ------------------------------
+// Insert
+// ------------------------------
+
+// Insert creates the token and returns its plaintext, which cannot be
+// recovered later.
+func (m *APITokenModel) Insert(token *APIToken) (string, error) {
+	secret, _, err := generateToken()
+	if err != nil {
+		return "", err
+	}
+
+	plaintext := apiTokenPrefix + secret
+	token.Prefix = plaintext[:len(apiTokenPrefix)+4]
+
+	query := `
+		INSERT INTO api_tokens (user_id, name, token_hash, prefix, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	args := []any{token.UserID, token.Name, hashToken(plaintext), token.Prefix, token.Scopes, token.ExpiresAt}
+	if err := m.DB.QueryRowContext(ctx, query, args...).Scan(&token.ID, &token.CreatedAt); err != nil {
+		return "", err
+	}
+
+	return plaintext, nil
+}
+
+// ------------------------------
+// Select
+// ------------------------------
+
+// GetForUser returns the user's tokens, expired ones included, newest first.
+func (m *APITokenModel) GetForUser(userID uuid.UUID) ([]APIToken, error) {
+	query := `
+		SELECT id, user_id, name, prefix, scopes, expires_at, last_used_at, created_at
+		FROM api_tokens
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []APIToken{}
+	for rows.Next() {
+		var token APIToken
+		if err := rows.Scan(
+			&token.ID,
+			&token.UserID,
+			&token.Name,
+			&token.Prefix,
+			scanTextArray(&token.Scopes),
+			&token.ExpiresAt,
+			&token.LastUsedAt,
+			&token.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// ------------------------------
+// Update
+// ------------------------------
+
+// Authenticate records the use of an unexpired token of an active user and
+// returns it with its user. Anything else yields ErrInvalidToken.
+func (m *APITokenModel) Authenticate(plaintext string) (*APIToken, *Principal, error) {
+	query := `
+		UPDATE api_tokens t
+		SET last_used_at = NOW()
+		FROM users u
+		WHERE t.token_hash = $1 AND t.expires_at > NOW()
+		AND u.id = t.user_id AND u.status = 'active'
+		RETURNING t.id, t.name, t.prefix, t.scopes, t.expires_at, t.last_used_at, t.created_at,
+			` + principalColumns
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	var token APIToken
+	var principal Principal
+	dest := []any{
+		&token.ID,
+		&token.Name,
+		&token.Prefix,
+		scanTextArray(&token.Scopes),
+		&token.ExpiresAt,
+		&token.LastUsedAt,
+		&token.CreatedAt,
+	}
+	if err := m.DB.QueryRowContext(ctx, query, hashToken(plaintext)).Scan(append(dest, principal.scanDest()...)...); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, nil, ErrInvalidToken
+		default:
+			return nil, nil, err
+		}
+	}
+	token.UserID = principal.UserID
+
+	return &token, &principal, nil
+}
+
+// ------------------------------
+// Delete
+// ------------------------------
+
+// Revoke deletes one of the user's tokens. It returns ErrRecordNotFound if the
+// user has no such token.
+func (m *APITokenModel) Revoke(id, userID uuid.UUID) error {
+	query := `
+		DELETE FROM api_tokens
+		WHERE id = $1 AND user_id = $2
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// RevokeAllForUser deletes every token of the user, such as when their
+// password changes.
+func (m *APITokenModel) RevokeAllForUser(userID uuid.UUID) error {
+	query := `
+		DELETE FROM api_tokens
+		WHERE user_id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, userID)
+	return err
+}
@@ -0,0 +1,160 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jonathanhu237/when-works/backend/internal/config"
+)
+
+var (
+	ErrInvalidClock = errors.New("invalid clock time, expected HH:MM")
+)
+
+// AvailabilityWindow is a recurring weekly window expressed in the owner's
+// local time. DayOfWeek follows time.Weekday (0 = Sunday).
+type AvailabilityWindow struct {
+	ID        uuid.UUID `json:"id"`
+	DayOfWeek int       `json:"day_of_week"`
+	StartTime string    `json:"start_time"`
+	EndTime   string    `json:"end_time"`
+}
+
+type Availability struct {
+	TimeZone string               `json:"time_zone"`
+	Windows  []AvailabilityWindow `json:"windows"`
+}
+
+type AvailabilityModel struct {
+	DB     *sql.DB
+	config config.Config
+}
+
+// ParseClock converts an "HH:MM" string into minutes since midnight. "24:00"
+// is accepted so that a window can run until the end of the day.
+func ParseClock(s string) (int, error) {
+	if len(s) != 5 || s[2] != ':' {
+		return 0, ErrInvalidClock
+	}
+	for _, i := range []int{0, 1, 3, 4} {
+		if s[i] < '0' || s[i] > '9' {
+			return 0, ErrInvalidClock
+		}
+	}
+
+	hours := int(s[0]-'0')*10 + int(s[1]-'0')
+	minutes := int(s[3]-'0')*10 + int(s[4]-'0')
+	if hours > 24 || minutes > 59 || (hours == 24 && minutes != 0) {
+		return 0, ErrInvalidClock
+	}
+
+	return hours*60 + minutes, nil
+}
+
+// ------------------------------
+// Select
+// ------------------------------
+func (m *AvailabilityModel) GetByUserID(userID uuid.UUID) (*Availability, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	availability := Availability{Windows: []AvailabilityWindow{}}
+
+	if err := m.DB.QueryRowContext(ctx, `SELECT time_zone FROM users WHERE id = $1`, userID).Scan(&availability.TimeZone); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	query := `
+		SELECT id, day_of_week, to_char(start_time, 'HH24:MI'), to_char(end_time, 'HH24:MI')
+		FROM availability_windows
+		WHERE user_id = $1
+		ORDER BY day_of_week, start_time
+	`
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var window AvailabilityWindow
+
+		if err := rows.Scan(
+			&window.ID,
+			&window.DayOfWeek,
+			&window.StartTime,
+			&window.EndTime,
+		); err != nil {
+			return nil, err
+		}
+
+		availability.Windows = append(availability.Windows, window)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &availability, nil
+}
+
+// ------------------------------
+// Replace
+// ------------------------------
+
+// Replace swaps the user's whole set of recurring windows and time zone in a
+// single transaction, so readers never observe a partially written set.
+func (m *AvailabilityModel) Replace(userID uuid.UUID, availability *Availability) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `UPDATE users SET time_zone = $1 WHERE id = $2`, availability.TimeZone, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM availability_windows WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO availability_windows (user_id, day_of_week, start_time, end_time)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`
+
+	for i := range availability.Windows {
+		window := &availability.Windows[i]
+
+		args := []any{userID, window.DayOfWeek, window.StartTime, window.EndTime}
+		if err := tx.QueryRowContext(ctx, query, args...).Scan(&window.ID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
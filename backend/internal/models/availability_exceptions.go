@@ -0,0 +1,401 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AvailabilityExceptionKind string
+
+const (
+	ExceptionUnavailable AvailabilityExceptionKind = "unavailable"
+	ExceptionAvailable   AvailabilityExceptionKind = "available"
+)
+
+// AvailabilityException is a one-off override of the recurring windows, such
+// as time off ("unavailable") or extra hours ("available").
+type AvailabilityException struct {
+	ID        uuid.UUID                 `json:"id"`
+	UserID    uuid.UUID                 `json:"user_id"`
+	Kind      AvailabilityExceptionKind `json:"kind"`
+	StartAt   time.Time                 `json:"start_at"`
+	EndAt     time.Time                 `json:"end_at"`
+	Note      string                    `json:"note"`
+	CreatedAt time.Time                 `json:"created_at"`
+}
+
+// ------------------------------
+// Insert
+// ------------------------------
+func (m *AvailabilityModel) InsertException(exception *AvailabilityException) error {
+	query := `
+		INSERT INTO availability_exceptions (user_id, kind, start_at, end_at, note)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	args := []any{exception.UserID, exception.Kind, exception.StartAt, exception.EndAt, exception.Note}
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&exception.ID, &exception.CreatedAt)
+}
+
+// ------------------------------
+// Select
+// ------------------------------
+func (m *AvailabilityModel) GetException(id, userID uuid.UUID) (*AvailabilityException, error) {
+	query := `
+		SELECT id, user_id, kind, start_at, end_at, note, created_at
+		FROM availability_exceptions
+		WHERE id = $1 AND user_id = $2
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	var exception AvailabilityException
+	if err := m.DB.QueryRowContext(ctx, query, id, userID).Scan(
+		&exception.ID,
+		&exception.UserID,
+		&exception.Kind,
+		&exception.StartAt,
+		&exception.EndAt,
+		&exception.Note,
+		&exception.CreatedAt,
+	); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &exception, nil
+}
+
+// GetExceptions returns the user's exceptions that overlap [from, to).
+func (m *AvailabilityModel) GetExceptions(userID uuid.UUID, from, to time.Time) ([]AvailabilityException, error) {
+	query := `
+		SELECT id, user_id, kind, start_at, end_at, note, created_at
+		FROM availability_exceptions
+		WHERE user_id = $1 AND start_at < $3 AND end_at > $2
+		ORDER BY start_at
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	exceptions := []AvailabilityException{}
+
+	for rows.Next() {
+		var exception AvailabilityException
+
+		if err := rows.Scan(
+			&exception.ID,
+			&exception.UserID,
+			&exception.Kind,
+			&exception.StartAt,
+			&exception.EndAt,
+			&exception.Note,
+			&exception.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		exceptions = append(exceptions, exception)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return exceptions, nil
+}
+
+// ------------------------------
+// Update
+// ------------------------------
+func (m *AvailabilityModel) UpdateException(exception *AvailabilityException) error {
+	query := `
+		UPDATE availability_exceptions
+		SET kind = $1, start_at = $2, end_at = $3, note = $4
+		WHERE id = $5 AND user_id = $6
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	args := []any{exception.Kind, exception.StartAt, exception.EndAt, exception.Note, exception.ID, exception.UserID}
+	result, err := m.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// ------------------------------
+// Delete
+// ------------------------------
+func (m *AvailabilityModel) DeleteException(id, userID uuid.UUID) error {
+	query := `
+		DELETE FROM availability_exceptions
+		WHERE id = $1 AND user_id = $2
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// ------------------------------
+// Resolve
+// ------------------------------
+
+// Resolve loads the user's recurring windows and exceptions and returns the
+// concrete UTC intervals in which they are available within [from, to).
+// Imported busy blocks are taken out last, so they win over "available"
+// exceptions too.
+func (m *AvailabilityModel) Resolve(userID uuid.UUID, from, to time.Time) ([]Interval, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	return resolveAvailability(ctx, m.DB, userID, from, to)
+}
+
+// resolveAvailability is Resolve on q, so that staffing checks can resolve
+// inside their transaction.
+func resolveAvailability(ctx context.Context, q queryer, userID uuid.UUID, from, to time.Time) ([]Interval, error) {
+	resolved, err := resolveMany(ctx, q, []uuid.UUID{userID}, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return resolved[userID], nil
+}
+
+// ResolveMany resolves the availability of several users over the same range.
+func (m *AvailabilityModel) ResolveMany(userIDs []uuid.UUID, from, to time.Time) (map[uuid.UUID][]Interval, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	return resolveMany(ctx, m.DB, userIDs, from, to)
+}
+
+// resolveMany loads the time zones, windows, exceptions and busy blocks of
+// all the users with one query each, however many users there are. It
+// returns ErrRecordNotFound if any of the users does not exist.
+func resolveMany(ctx context.Context, q queryer, userIDs []uuid.UUID, from, to time.Time) (map[uuid.UUID][]Interval, error) {
+	resolved := make(map[uuid.UUID][]Interval, len(userIDs))
+	if len(userIDs) == 0 {
+		return resolved, nil
+	}
+
+	ids := make([]string, len(userIDs))
+	for i, userID := range userIDs {
+		ids[i] = userID.String()
+	}
+
+	availabilities := make(map[uuid.UUID]*Availability, len(userIDs))
+	err := queryRows(ctx, q, `SELECT id, time_zone FROM users WHERE id = ANY($1::uuid[])`, []any{ids}, func(rows *sql.Rows) error {
+		var userID uuid.UUID
+		availability := Availability{Windows: []AvailabilityWindow{}}
+		if err := rows.Scan(&userID, &availability.TimeZone); err != nil {
+			return err
+		}
+		availabilities[userID] = &availability
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, userID := range userIDs {
+		if availabilities[userID] == nil {
+			return nil, ErrRecordNotFound
+		}
+	}
+
+	query := `
+		SELECT user_id, id, day_of_week, to_char(start_time, 'HH24:MI'), to_char(end_time, 'HH24:MI')
+		FROM availability_windows
+		WHERE user_id = ANY($1::uuid[])
+		ORDER BY day_of_week, start_time
+	`
+	err = queryRows(ctx, q, query, []any{ids}, func(rows *sql.Rows) error {
+		var userID uuid.UUID
+		var window AvailabilityWindow
+		if err := rows.Scan(&userID, &window.ID, &window.DayOfWeek, &window.StartTime, &window.EndTime); err != nil {
+			return err
+		}
+		availabilities[userID].Windows = append(availabilities[userID].Windows, window)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	exceptions := make(map[uuid.UUID][]AvailabilityException, len(userIDs))
+	query = `
+		SELECT id, user_id, kind, start_at, end_at, note, created_at
+		FROM availability_exceptions
+		WHERE user_id = ANY($1::uuid[]) AND start_at < $3 AND end_at > $2
+		ORDER BY start_at
+	`
+	err = queryRows(ctx, q, query, []any{ids, from, to}, func(rows *sql.Rows) error {
+		var exception AvailabilityException
+		if err := rows.Scan(
+			&exception.ID,
+			&exception.UserID,
+			&exception.Kind,
+			&exception.StartAt,
+			&exception.EndAt,
+			&exception.Note,
+			&exception.CreatedAt,
+		); err != nil {
+			return err
+		}
+		exceptions[exception.UserID] = append(exceptions[exception.UserID], exception)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	busy := make(map[uuid.UUID][]Interval, len(userIDs))
+	query = `
+		SELECT user_id, start_at, end_at
+		FROM busy_blocks
+		WHERE user_id = ANY($1::uuid[]) AND start_at < $3 AND end_at > $2
+	`
+	err = queryRows(ctx, q, query, []any{ids, from, to}, func(rows *sql.Rows) error {
+		var userID uuid.UUID
+		var block Interval
+		if err := rows.Scan(&userID, &block.Start, &block.End); err != nil {
+			return err
+		}
+		busy[userID] = append(busy[userID], Interval{Start: block.Start.UTC(), End: block.End.UTC()})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Busy blocks are taken out last, so they win over "available" exceptions
+	for userID, availability := range availabilities {
+		intervals, err := ResolveAvailability(availability, exceptions[userID], from, to)
+		if err != nil {
+			return nil, err
+		}
+		resolved[userID] = SubtractIntervals(intervals, busy[userID])
+	}
+
+	return resolved, nil
+}
+
+// queryRows runs the query on q and calls scan for every row.
+func queryRows(ctx context.Context, q queryer, query string, args []any, scan func(*sql.Rows) error) error {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// ResolveAvailability expands the recurring windows in the availability's time
+// zone, adds "available" exceptions, removes "unavailable" exceptions and clips
+// the result to [from, to). The returned intervals are merged and in UTC.
+func ResolveAvailability(availability *Availability, exceptions []AvailabilityException, from, to time.Time) ([]Interval, error) {
+	loc, err := time.LoadLocation(availability.TimeZone)
+	if err != nil {
+		return nil, err
+	}
+
+	var intervals []Interval
+
+	// Start a day early so windows that began before from in local time are
+	// still considered.
+	localFrom := from.In(loc)
+	day := time.Date(localFrom.Year(), localFrom.Month(), localFrom.Day()-1, 0, 0, 0, 0, loc)
+	for day.Before(to) {
+		for _, window := range availability.Windows {
+			if window.DayOfWeek != int(day.Weekday()) {
+				continue
+			}
+
+			start, err := ParseClock(window.StartTime)
+			if err != nil {
+				return nil, err
+			}
+			end, err := ParseClock(window.EndTime)
+			if err != nil {
+				return nil, err
+			}
+
+			intervals = append(intervals, Interval{
+				Start: time.Date(day.Year(), day.Month(), day.Day(), start/60, start%60, 0, 0, loc).UTC(),
+				End:   time.Date(day.Year(), day.Month(), day.Day(), end/60, end%60, 0, 0, loc).UTC(),
+			})
+		}
+		day = time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, loc)
+	}
+
+	var unavailable []Interval
+	for _, exception := range exceptions {
+		interval := Interval{Start: exception.StartAt.UTC(), End: exception.EndAt.UTC()}
+		switch exception.Kind {
+		case ExceptionAvailable:
+			intervals = append(intervals, interval)
+		case ExceptionUnavailable:
+			unavailable = append(unavailable, interval)
+		}
+	}
+
+	intervals = SubtractIntervals(intervals, unavailable)
+
+	return MergeIntervals(ClipIntervals(intervals, from.UTC(), to.UTC())), nil
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BusyBlock is time taken up in an external calendar. Busy blocks are
+// imported as a whole and subtracted from resolved availability.
+type BusyBlock struct {
+	ID      uuid.UUID `json:"id"`
+	StartAt time.Time `json:"start_at"`
+	EndAt   time.Time `json:"end_at"`
+	Summary string    `json:"summary"`
+}
+
+// ------------------------------
+// Replace
+// ------------------------------
+
+// ReplaceBusyBlocks swaps the user's imported busy blocks for blocks, so
+// importing the same calendar twice leaves the same result.
+func (m *AvailabilityModel) ReplaceBusyBlocks(userID uuid.UUID, blocks []BusyBlock) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM busy_blocks WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	if len(blocks) > 0 {
+		starts := make([]time.Time, len(blocks))
+		ends := make([]time.Time, len(blocks))
+		summaries := make([]string, len(blocks))
+		for i, block := range blocks {
+			starts[i], ends[i], summaries[i] = block.StartAt, block.EndAt, block.Summary
+		}
+
+		query := `
+			INSERT INTO busy_blocks (user_id, start_at, end_at, summary)
+			SELECT $1, start_at, end_at, summary
+			FROM unnest($2::timestamptz[], $3::timestamptz[], $4::text[]) AS b(start_at, end_at, summary)
+		`
+		if _, err := tx.ExecContext(ctx, query, userID, starts, ends, summaries); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ------------------------------
+// Select
+// ------------------------------
+
+// GetBusyBlocks returns the user's busy blocks that overlap [from, to).
+func (m *AvailabilityModel) GetBusyBlocks(userID uuid.UUID, from, to time.Time) ([]BusyBlock, error) {
+	query := `
+		SELECT id, start_at, end_at, summary
+		FROM busy_blocks
+		WHERE user_id = $1 AND start_at < $3 AND end_at > $2
+		ORDER BY start_at
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	blocks := []BusyBlock{}
+
+	for rows.Next() {
+		var block BusyBlock
+		if err := rows.Scan(&block.ID, &block.StartAt, &block.EndAt, &block.Summary); err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, block)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return blocks, nil
+}
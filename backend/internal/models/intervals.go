@@ -0,0 +1,104 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+// Interval is a half-open [Start, End) span of time in UTC.
+type Interval struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+func (i Interval) Duration() time.Duration {
+	return i.End.Sub(i.Start)
+}
+
+// Covers reports whether the interval fully contains [start, end).
+func (i Interval) Covers(start, end time.Time) bool {
+	return !i.Start.After(start) && !i.End.Before(end)
+}
+
+// Overlaps reports whether the interval shares any time with [start, end).
+func (i Interval) Overlaps(start, end time.Time) bool {
+	return i.Start.Before(end) && start.Before(i.End)
+}
+
+// MergeIntervals sorts the intervals and joins any that overlap or touch. Empty
+// intervals are dropped.
+func MergeIntervals(intervals []Interval) []Interval {
+	sorted := make([]Interval, 0, len(intervals))
+	for _, interval := range intervals {
+		if interval.Start.Before(interval.End) {
+			sorted = append(sorted, interval)
+		}
+	}
+
+	slices.SortFunc(sorted, func(a, b Interval) int {
+		return a.Start.Compare(b.Start)
+	})
+
+	merged := make([]Interval, 0, len(sorted))
+	for _, interval := range sorted {
+		last := len(merged) - 1
+		if last >= 0 && !interval.Start.After(merged[last].End) {
+			if interval.End.After(merged[last].End) {
+				merged[last].End = interval.End
+			}
+			continue
+		}
+		merged = append(merged, interval)
+	}
+
+	return merged
+}
+
+// SubtractIntervals removes every span in cut from base.
+func SubtractIntervals(base, cut []Interval) []Interval {
+	base = MergeIntervals(base)
+	cut = MergeIntervals(cut)
+
+	result := make([]Interval, 0, len(base))
+	for _, interval := range base {
+		remaining := []Interval{interval}
+		for _, c := range cut {
+			var next []Interval
+			for _, r := range remaining {
+				if !r.Overlaps(c.Start, c.End) {
+					next = append(next, r)
+					continue
+				}
+				if r.Start.Before(c.Start) {
+					next = append(next, Interval{Start: r.Start, End: c.Start})
+				}
+				if c.End.Before(r.End) {
+					next = append(next, Interval{Start: c.End, End: r.End})
+				}
+			}
+			remaining = next
+		}
+		result = append(result, remaining...)
+	}
+
+	return result
+}
+
+// ClipIntervals trims the intervals to [from, to).
+func ClipIntervals(intervals []Interval, from, to time.Time) []Interval {
+	clipped := make([]Interval, 0, len(intervals))
+	for _, interval := range intervals {
+		if !interval.Overlaps(from, to) {
+			continue
+		}
+		if interval.Start.Before(from) {
+			interval.Start = from
+		}
+		if interval.End.After(to) {
+			interval.End = to
+		}
+		clipped = append(clipped, interval)
+	}
+
+	return clipped
+}
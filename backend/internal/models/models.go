@@ -12,11 +12,37 @@ var (
 )
 
 type Models struct {
-	User UserModel
+	User         UserModel
+	Availability AvailabilityModel
+	Shift        ShiftModel
+	Schedule     ScheduleModel
+	Swap         SwapModel
+	TimeOff      TimeOffModel
+	Poll         PollModel
+	Session      SessionModel
+	Token        TokenModel
+	TOTP         TOTPModel
+	APIToken     APITokenModel
+	Role         RoleModel
+	Team         TeamModel
+	Organization OrganizationModel
 }
 
 func New(db *sql.DB, cfg config.Config) Models {
 	return Models{
-		User: UserModel{DB: db, config: cfg},
+		User:         UserModel{DB: db, config: cfg},
+		Availability: AvailabilityModel{DB: db, config: cfg},
+		Shift:        ShiftModel{DB: db, config: cfg},
+		Schedule:     ScheduleModel{DB: db, config: cfg},
+		Swap:         SwapModel{DB: db, config: cfg},
+		TimeOff:      TimeOffModel{DB: db, config: cfg},
+		Poll:         PollModel{DB: db, config: cfg},
+		Session:      SessionModel{DB: db, config: cfg},
+		Token:        TokenModel{DB: db, config: cfg},
+		TOTP:         TOTPModel{DB: db, config: cfg},
+		APIToken:     APITokenModel{DB: db, config: cfg},
+		Role:         RoleModel{DB: db, config: cfg},
+		Team:         TeamModel{DB: db, config: cfg},
+		Organization: OrganizationModel{DB: db, config: cfg},
 	}
 }
@@ -0,0 +1,155 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jonathanhu237/when-works/backend/internal/config"
+)
+
+var (
+	ErrOrganizationNameConflict = errors.New("organization name already exists")
+)
+
+// Organization owns users and all their scheduling data. No query reaches
+// across organizations. The host organization runs the deployment and is the
+// only one allowed to create others.
+type Organization struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	Host      bool      `json:"host"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type OrganizationModel struct {
+	DB     *sql.DB
+	config config.Config
+}
+
+// ------------------------------
+// Insert
+// ------------------------------
+
+// Insert creates the organization together with its first user, who is made
+// its superadmin. Nothing is created if either insert fails.
+func (m *OrganizationModel) Insert(org *Organization, admin *User) error {
+	query := `
+		INSERT INTO organizations (name, host)
+		VALUES ($1, $2)
+		RETURNING id, created_at
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRowContext(ctx, query, org.Name, org.Host).Scan(&org.ID, &org.CreatedAt); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation && pgErr.ConstraintName == "organizations_name_key" {
+			return ErrOrganizationNameConflict
+		}
+		return err
+	}
+
+	admin.OrgID = org.ID
+	if err := insertUser(ctx, tx, admin); err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO user_roles (user_id, role_id)
+		SELECT $1, id FROM roles WHERE name = $2 AND built_in
+	`, admin.ID, RoleSuperadmin)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrUnknownRole
+	}
+	admin.Roles = []string{RoleSuperadmin}
+
+	return tx.Commit()
+}
+
+// ------------------------------
+// Select
+// ------------------------------
+func (m *OrganizationModel) GetAll() ([]Organization, error) {
+	query := `
+		SELECT id, name, host, created_at
+		FROM organizations
+		ORDER BY host DESC, name
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orgs := []Organization{}
+	for rows.Next() {
+		var org Organization
+		if err := rows.Scan(&org.ID, &org.Name, &org.Host, &org.CreatedAt); err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, org)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return orgs, nil
+}
+
+func (m *OrganizationModel) GetByID(id uuid.UUID) (*Organization, error) {
+	return m.get(`id = $1`, id)
+}
+
+// GetHost returns the host organization, or ErrRecordNotFound before the
+// first start.
+func (m *OrganizationModel) GetHost() (*Organization, error) {
+	return m.get(`host`)
+}
+
+func (m *OrganizationModel) get(where string, args ...any) (*Organization, error) {
+	query := `
+		SELECT id, name, host, created_at
+		FROM organizations
+		WHERE ` + where
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	var org Organization
+	if err := m.DB.QueryRowContext(ctx, query, args...).Scan(&org.ID, &org.Name, &org.Host, &org.CreatedAt); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &org, nil
+}
@@ -0,0 +1,122 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jonathanhu237/when-works/backend/internal/config"
+	"github.com/jonathanhu237/when-works/backend/internal/testdb"
+)
+
+// tenant is an organization with one record of each kind.
+type tenant struct {
+	org      Organization
+	admin    User
+	shift    Shift
+	schedule Schedule
+	team     Team
+	poll     Poll
+	token    APIToken
+}
+
+func newTenant(t *testing.T, m Models, name string) tenant {
+	t.Helper()
+
+	tn := tenant{
+		org:   Organization{Name: name},
+		admin: User{Username: name + "-admin", Email: name + "@example.com", Name: name, Status: UserStatusActive},
+	}
+	if err := m.Organization.Insert(&tn.org, &tn.admin); err != nil {
+		t.Fatalf("insert organization %s: %v", name, err)
+	}
+
+	start := time.Date(2025, time.March, 3, 9, 0, 0, 0, time.UTC)
+
+	tn.shift = Shift{OrgID: tn.org.ID, Title: "Morning", StartAt: start, EndAt: start.Add(8 * time.Hour), RequiredHeadcount: 1}
+	if err := m.Shift.Insert(&tn.shift); err != nil {
+		t.Fatalf("insert shift: %v", err)
+	}
+
+	tn.schedule = Schedule{OrgID: tn.org.ID, Title: "March", PeriodStart: start, PeriodEnd: start.AddDate(0, 1, 0)}
+	if err := m.Schedule.Insert(&tn.schedule); err != nil {
+		t.Fatalf("insert schedule: %v", err)
+	}
+
+	tn.team = Team{OrgID: tn.org.ID, Name: "Kitchen"}
+	if err := m.Team.Insert(&tn.team); err != nil {
+		t.Fatalf("insert team: %v", err)
+	}
+
+	tn.poll = Poll{
+		OrgID:   tn.org.ID,
+		OwnerID: tn.admin.ID,
+		Title:   "Team meeting",
+		Options: []PollOption{{StartAt: start, EndAt: start.Add(time.Hour)}},
+	}
+	if _, err := m.Poll.Insert(&tn.poll, nil); err != nil {
+		t.Fatalf("insert poll: %v", err)
+	}
+
+	tn.token = APIToken{UserID: tn.admin.ID, Name: "CI", Scopes: []string{APIScopeRead}, ExpiresAt: time.Now().Add(time.Hour)}
+	if _, err := m.APIToken.Insert(&tn.token); err != nil {
+		t.Fatalf("insert API token: %v", err)
+	}
+
+	return tn
+}
+
+func TestOrganizationIsolation(t *testing.T) {
+	cfg := config.Config{Database: config.DatabaseConfig{QueryTimeout: 5}}
+	m := New(testdb.New(t), cfg)
+
+	a := newTenant(t, m, "a")
+	b := newTenant(t, m, "b")
+
+	inScope, err := m.User.InScope(b.admin.ID, AllUsers(a.org.ID).WithArchived())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inScope {
+		t.Error("User.InScope: a user of another organization is in scope")
+	}
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"User.Deactivate", func() error { return m.User.Deactivate(a.org.ID, b.admin.ID) }},
+		{"Shift.GetByID", func() error { _, err := m.Shift.GetByID(a.org.ID, b.shift.ID); return err }},
+		{"Shift.Assign foreign shift", func() error { _, err := m.Shift.Assign(a.org.ID, b.shift.ID, a.admin.ID); return err }},
+		{"Shift.Assign foreign user", func() error { _, err := m.Shift.Assign(a.org.ID, a.shift.ID, b.admin.ID); return err }},
+		{"Shift.Delete", func() error { return m.Shift.Delete(a.org.ID, b.shift.ID) }},
+		{"Schedule.GetByID", func() error { _, err := m.Schedule.GetByID(a.org.ID, b.schedule.ID); return err }},
+		{"Team.GetByID", func() error { _, err := m.Team.GetByID(a.org.ID, b.team.ID); return err }},
+		{"Team.SetMember", func() error { return m.Team.SetMember(a.org.ID, b.team.ID, a.admin.ID, false) }},
+		{"Team.Delete", func() error { return m.Team.Delete(a.org.ID, b.team.ID) }},
+		{"Poll.GetByID", func() error { _, err := m.Poll.GetByID(a.org.ID, b.poll.ID); return err }},
+		{"APIToken.Revoke", func() error { return m.APIToken.Revoke(b.token.ID, a.admin.ID) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); !errors.Is(err, ErrRecordNotFound) {
+				t.Errorf("got %v, want %v", err, ErrRecordNotFound)
+			}
+		})
+	}
+
+	// The foreign records must have survived the attempts above
+	if _, err := m.Shift.GetByID(b.org.ID, b.shift.ID); err != nil {
+		t.Errorf("shift of the other organization: %v", err)
+	}
+	if _, err := m.Team.GetByID(b.org.ID, b.team.ID); err != nil {
+		t.Errorf("team of the other organization: %v", err)
+	}
+	if tokens, err := m.APIToken.GetForUser(b.admin.ID); err != nil || len(tokens) != 1 {
+		t.Errorf("tokens of the other organization: %v, %v", tokens, err)
+	}
+	if user, err := m.User.GetByID(b.admin.ID); err != nil || user.Status != UserStatusActive {
+		t.Errorf("user of the other organization: %v, %v", user, err)
+	}
+}
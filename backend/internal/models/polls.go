@@ -0,0 +1,686 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jonathanhu237/when-works/backend/internal/config"
+)
+
+var (
+	ErrPollClosed        = errors.New("poll is closed")
+	ErrInvalidPollOption = errors.New("option does not belong to the poll")
+)
+
+type PollStatus string
+
+const (
+	PollOpen   PollStatus = "open"
+	PollClosed PollStatus = "closed"
+)
+
+type PollChoice string
+
+const (
+	PollYes   PollChoice = "yes"
+	PollMaybe PollChoice = "maybe"
+	PollNo    PollChoice = "no"
+)
+
+// Poll proposes candidate time slots for a meeting. Participants answer
+// through their own tokenized link, so they do not need an account.
+type Poll struct {
+	ID                uuid.UUID         `json:"id"`
+	OrgID             uuid.UUID         `json:"-"`
+	OwnerID           uuid.UUID         `json:"owner_id"`
+	Title             string            `json:"title"`
+	Description       string            `json:"description"`
+	Status            PollStatus        `json:"status"`
+	ConfirmedOptionID *uuid.UUID        `json:"confirmed_option_id"`
+	Options           []PollOption      `json:"options"`
+	Participants      []PollParticipant `json:"participants,omitempty"`
+	CreatedAt         time.Time         `json:"created_at"`
+	UpdatedAt         time.Time         `json:"updated_at"`
+}
+
+type PollOption struct {
+	ID      uuid.UUID `json:"id"`
+	StartAt time.Time `json:"start_at"`
+	EndAt   time.Time `json:"end_at"`
+}
+
+type PollParticipant struct {
+	ID        uuid.UUID  `json:"id"`
+	UserID    *uuid.UUID `json:"user_id"`
+	Email     string     `json:"email"`
+	Name      string     `json:"name"`
+	Responded bool       `json:"responded"`
+}
+
+// PollInvitee is someone to invite, either a user or an external address.
+type PollInvitee struct {
+	UserID *uuid.UUID
+	Email  string
+	Name   string
+}
+
+// PollInvitation pairs a new participant with the plaintext token of their
+// voting link. The token is not stored and cannot be recovered later.
+type PollInvitation struct {
+	Participant PollParticipant
+	Token       string
+}
+
+type PollVote struct {
+	OptionID uuid.UUID  `json:"option_id"`
+	Choice   PollChoice `json:"choice"`
+}
+
+type PollTally struct {
+	OptionID uuid.UUID `json:"option_id"`
+	Yes      int       `json:"yes"`
+	Maybe    int       `json:"maybe"`
+	No       int       `json:"no"`
+}
+
+// PollMeeting is the confirmed time of a closed poll.
+type PollMeeting struct {
+	PollID      uuid.UUID `json:"poll_id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	StartAt     time.Time `json:"start_at"`
+	EndAt       time.Time `json:"end_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type PollModel struct {
+	DB     *sql.DB
+	config config.Config
+}
+
+// ------------------------------
+// Insert
+// ------------------------------
+
+// Insert creates the poll with its options and invites the invitees.
+func (m *PollModel) Insert(poll *Poll, invitees []PollInvitee) ([]PollInvitation, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO polls (org_id, owner_id, title, description)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, status, created_at, updated_at
+	`
+	args := []any{poll.OrgID, poll.OwnerID, poll.Title, poll.Description}
+	if err := tx.QueryRowContext(ctx, query, args...).Scan(&poll.ID, &poll.Status, &poll.CreatedAt, &poll.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	for i := range poll.Options {
+		option := &poll.Options[i]
+		query := `
+			INSERT INTO poll_options (poll_id, start_at, end_at)
+			VALUES ($1, $2, $3)
+			RETURNING id
+		`
+		if err := tx.QueryRowContext(ctx, query, poll.ID, option.StartAt, option.EndAt).Scan(&option.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	invitations, err := m.invite(ctx, tx, poll.ID, invitees)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	poll.Participants = make([]PollParticipant, 0, len(invitations))
+	for _, invitation := range invitations {
+		poll.Participants = append(poll.Participants, invitation.Participant)
+	}
+
+	return invitations, nil
+}
+
+// AddParticipants invites more people to an open poll. Addresses that are
+// already invited are skipped, so only new invitations are returned.
+func (m *PollModel) AddParticipants(pollID uuid.UUID, invitees []PollInvitee) ([]PollInvitation, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := m.lockOpen(ctx, tx, pollID); err != nil {
+		return nil, err
+	}
+
+	invitations, err := m.invite(ctx, tx, pollID, invitees)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return invitations, nil
+}
+
+func (m *PollModel) invite(ctx context.Context, tx *sql.Tx, pollID uuid.UUID, invitees []PollInvitee) ([]PollInvitation, error) {
+	invitations := []PollInvitation{}
+
+	for _, invitee := range invitees {
+		token, tokenHash, err := generateToken()
+		if err != nil {
+			return nil, err
+		}
+
+		participant := PollParticipant{
+			UserID: invitee.UserID,
+			Email:  strings.ToLower(invitee.Email),
+			Name:   invitee.Name,
+		}
+
+		query := `
+			INSERT INTO poll_participants (poll_id, user_id, email, name, token_hash)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT ON CONSTRAINT poll_participants_poll_id_email_key DO NOTHING
+			RETURNING id
+		`
+		args := []any{pollID, participant.UserID, participant.Email, participant.Name, tokenHash}
+		if err := tx.QueryRowContext(ctx, query, args...).Scan(&participant.ID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+			return nil, err
+		}
+
+		invitations = append(invitations, PollInvitation{Participant: participant, Token: token})
+	}
+
+	return invitations, nil
+}
+
+// ------------------------------
+// Select
+// ------------------------------
+
+// GetByID returns the poll of the organization with its options and
+// participants.
+func (m *PollModel) GetByID(orgID, id uuid.UUID) (*Poll, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	poll, err := m.get(ctx, `WHERE id = $1 AND org_id = $2`, id, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT p.id, p.user_id, p.email, p.name, EXISTS(SELECT 1 FROM poll_votes v WHERE v.participant_id = p.id)
+		FROM poll_participants p
+		WHERE p.poll_id = $1
+		ORDER BY p.created_at, p.email
+	`
+	rows, err := m.DB.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	poll.Participants = []PollParticipant{}
+	for rows.Next() {
+		var participant PollParticipant
+		if err := rows.Scan(&participant.ID, &participant.UserID, &participant.Email, &participant.Name, &participant.Responded); err != nil {
+			return nil, err
+		}
+		poll.Participants = append(poll.Participants, participant)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return poll, nil
+}
+
+// GetByToken returns the poll and the participant a voting link belongs to.
+// The poll's participant list is left empty so voters do not see each other.
+func (m *PollModel) GetByToken(token string) (*Poll, *PollParticipant, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	var pollID uuid.UUID
+	var participant PollParticipant
+
+	query := `
+		SELECT p.poll_id, p.id, p.user_id, p.email, p.name, EXISTS(SELECT 1 FROM poll_votes v WHERE v.participant_id = p.id)
+		FROM poll_participants p
+		WHERE p.token_hash = $1
+	`
+	if err := m.DB.QueryRowContext(ctx, query, hashToken(token)).Scan(
+		&pollID,
+		&participant.ID,
+		&participant.UserID,
+		&participant.Email,
+		&participant.Name,
+		&participant.Responded,
+	); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, nil, ErrRecordNotFound
+		default:
+			return nil, nil, err
+		}
+	}
+
+	poll, err := m.get(ctx, `WHERE id = $1`, pollID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return poll, &participant, nil
+}
+
+// GetForUser returns the polls the user owns or has been invited to.
+func (m *PollModel) GetForUser(userID uuid.UUID) ([]Poll, error) {
+	query := `
+		SELECT id, org_id, owner_id, title, description, status, confirmed_option_id, created_at, updated_at
+		FROM polls
+		WHERE owner_id = $1 OR id IN (SELECT poll_id FROM poll_participants WHERE user_id = $1)
+		ORDER BY created_at DESC
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	polls := []Poll{}
+
+	for rows.Next() {
+		var poll Poll
+		if err := rows.Scan(
+			&poll.ID,
+			&poll.OrgID,
+			&poll.OwnerID,
+			&poll.Title,
+			&poll.Description,
+			&poll.Status,
+			&poll.ConfirmedOptionID,
+			&poll.CreatedAt,
+			&poll.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		polls = append(polls, poll)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range polls {
+		if polls[i].Options, err = m.getOptions(ctx, polls[i].ID); err != nil {
+			return nil, err
+		}
+	}
+
+	return polls, nil
+}
+
+// GetMeetingsForUser returns the confirmed meetings overlapping [from, to)
+// that the user organised or was invited to.
+func (m *PollModel) GetMeetingsForUser(userID uuid.UUID, from, to time.Time) ([]PollMeeting, error) {
+	query := `
+		SELECT p.id, p.title, p.description, o.start_at, o.end_at, p.updated_at
+		FROM polls p
+		JOIN poll_options o ON o.id = p.confirmed_option_id
+		WHERE (p.owner_id = $1 OR p.id IN (SELECT poll_id FROM poll_participants WHERE user_id = $1))
+			AND o.start_at < $3 AND o.end_at > $2
+		ORDER BY o.start_at, p.id
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	meetings := []PollMeeting{}
+
+	for rows.Next() {
+		var meeting PollMeeting
+		if err := rows.Scan(
+			&meeting.PollID,
+			&meeting.Title,
+			&meeting.Description,
+			&meeting.StartAt,
+			&meeting.EndAt,
+			&meeting.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		meetings = append(meetings, meeting)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return meetings, nil
+}
+
+// IsParticipant reports whether the user has been invited to the poll.
+func (m *PollModel) IsParticipant(pollID, userID uuid.UUID) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM poll_participants WHERE poll_id = $1 AND user_id = $2)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	var exists bool
+	if err := m.DB.QueryRowContext(ctx, query, pollID, userID).Scan(&exists); err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+func (m *PollModel) GetVotes(participantID uuid.UUID) ([]PollVote, error) {
+	query := `
+		SELECT v.option_id, v.choice
+		FROM poll_votes v
+		JOIN poll_options o ON o.id = v.option_id
+		WHERE v.participant_id = $1
+		ORDER BY o.start_at, o.id
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, participantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	votes := []PollVote{}
+
+	for rows.Next() {
+		var vote PollVote
+		if err := rows.Scan(&vote.OptionID, &vote.Choice); err != nil {
+			return nil, err
+		}
+		votes = append(votes, vote)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return votes, nil
+}
+
+// Tally counts the answers per option, in option order. Options nobody has
+// answered yet are included with zero counts.
+func (m *PollModel) Tally(pollID uuid.UUID) ([]PollTally, error) {
+	query := `
+		SELECT o.id,
+			COUNT(*) FILTER (WHERE v.choice = 'yes'),
+			COUNT(*) FILTER (WHERE v.choice = 'maybe'),
+			COUNT(*) FILTER (WHERE v.choice = 'no')
+		FROM poll_options o
+		LEFT JOIN poll_votes v ON v.option_id = o.id
+		WHERE o.poll_id = $1
+		GROUP BY o.id, o.start_at
+		ORDER BY o.start_at, o.id
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pollID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tally := []PollTally{}
+
+	for rows.Next() {
+		var option PollTally
+		if err := rows.Scan(&option.OptionID, &option.Yes, &option.Maybe, &option.No); err != nil {
+			return nil, err
+		}
+		tally = append(tally, option)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tally, nil
+}
+
+func (m *PollModel) get(ctx context.Context, where string, args ...any) (*Poll, error) {
+	query := `
+		SELECT id, org_id, owner_id, title, description, status, confirmed_option_id, created_at, updated_at
+		FROM polls
+	` + where
+
+	var poll Poll
+	if err := m.DB.QueryRowContext(ctx, query, args...).Scan(
+		&poll.ID,
+		&poll.OrgID,
+		&poll.OwnerID,
+		&poll.Title,
+		&poll.Description,
+		&poll.Status,
+		&poll.ConfirmedOptionID,
+		&poll.CreatedAt,
+		&poll.UpdatedAt,
+	); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	options, err := m.getOptions(ctx, poll.ID)
+	if err != nil {
+		return nil, err
+	}
+	poll.Options = options
+
+	return &poll, nil
+}
+
+func (m *PollModel) getOptions(ctx context.Context, pollID uuid.UUID) ([]PollOption, error) {
+	query := `
+		SELECT id, start_at, end_at
+		FROM poll_options
+		WHERE poll_id = $1
+		ORDER BY start_at, id
+	`
+
+	rows, err := m.DB.QueryContext(ctx, query, pollID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	options := []PollOption{}
+
+	for rows.Next() {
+		var option PollOption
+		if err := rows.Scan(&option.ID, &option.StartAt, &option.EndAt); err != nil {
+			return nil, err
+		}
+		options = append(options, option)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return options, nil
+}
+
+// ------------------------------
+// Update
+// ------------------------------
+
+// SetVotes records the participant's answers. Options not mentioned keep
+// their previous answer.
+func (m *PollModel) SetVotes(pollID, participantID uuid.UUID, votes []PollVote) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := m.lockOpen(ctx, tx, pollID); err != nil {
+		return err
+	}
+
+	for _, vote := range votes {
+		query := `
+			INSERT INTO poll_votes (participant_id, option_id, choice)
+			SELECT $1, o.id, $3
+			FROM poll_options o
+			WHERE o.id = $2 AND o.poll_id = $4
+			ON CONFLICT (participant_id, option_id) DO UPDATE SET choice = EXCLUDED.choice, updated_at = NOW()
+		`
+		result, err := tx.ExecContext(ctx, query, participantID, vote.OptionID, vote.Choice, pollID)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return ErrInvalidPollOption
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Close stops voting. When optionID is set, that option becomes the confirmed
+// meeting time.
+func (m *PollModel) Close(pollID uuid.UUID, optionID *uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := m.lockOpen(ctx, tx, pollID); err != nil {
+		return err
+	}
+
+	if optionID != nil {
+		var exists bool
+		query := `SELECT EXISTS(SELECT 1 FROM poll_options WHERE id = $1 AND poll_id = $2)`
+		if err := tx.QueryRowContext(ctx, query, *optionID, pollID).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return ErrInvalidPollOption
+		}
+	}
+
+	query := `
+		UPDATE polls
+		SET status = 'closed', confirmed_option_id = $1, updated_at = NOW()
+		WHERE id = $2
+	`
+	if _, err := tx.ExecContext(ctx, query, optionID, pollID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m *PollModel) lockOpen(ctx context.Context, tx *sql.Tx, pollID uuid.UUID) error {
+	var status PollStatus
+	if err := tx.QueryRowContext(ctx, `SELECT status FROM polls WHERE id = $1 FOR UPDATE`, pollID).Scan(&status); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	if status != PollOpen {
+		return ErrPollClosed
+	}
+
+	return nil
+}
+
+// ------------------------------
+// Delete
+// ------------------------------
+func (m *PollModel) Delete(id uuid.UUID) error {
+	query := `
+		DELETE FROM polls
+		WHERE id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,469 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jonathanhu237/when-works/backend/internal/config"
+)
+
+var (
+	ErrRoleNameConflict  = errors.New("role name already exists")
+	ErrRoleBuiltIn       = errors.New("built-in roles cannot be changed")
+	ErrUnknownPermission = errors.New("unknown permission")
+	ErrUnknownRole       = errors.New("unknown role")
+	ErrLastSuperadmin    = errors.New("at least one user must keep the superadmin role")
+)
+
+// RoleSuperadmin is the built-in role holding every permission. Existing
+// admins were moved to it when roles were introduced.
+const RoleSuperadmin = "superadmin"
+
+// Permissions checked by the API. The permissions table lists the same names
+// with a description each.
+const (
+	PermUsersRead           = "users.read"
+	PermUsersManage         = "users.manage"
+	PermUsersPurge          = "users.purge"
+	PermRolesManage         = "roles.manage"
+	PermShiftsRead          = "shifts.read"
+	PermShiftsManage        = "shifts.manage"
+	PermTimeOffRead         = "time_off.read"
+	PermTimeOffManage       = "time_off.manage"
+	PermSwapsRead           = "swaps.read"
+	PermSwapsManage         = "swaps.manage"
+	PermTeamsManage         = "teams.manage"
+	PermOrganizationsManage = "organizations.manage"
+)
+
+// Principal is a user as authorization sees them: who they are, which
+// organization they belong to, what their roles currently allow and which
+// teams they manage.
+type Principal struct {
+	UserID         uuid.UUID
+	OrgID          uuid.UUID
+	Username       string
+	Permissions    []string
+	ManagedTeamIDs []uuid.UUID
+	// Superadmin is set if the user holds the built-in superadmin role.
+	Superadmin bool
+	// TOTPEnabled is set if the user has confirmed two-factor authentication.
+	TOTPEnabled bool
+}
+
+// principalColumns selects a Principal from the users row aliased u.
+const principalColumns = `u.id, u.org_id, u.username,
+			ARRAY(
+				SELECT DISTINCT rp.permission
+				FROM user_roles ur
+				JOIN role_permissions rp ON rp.role_id = ur.role_id
+				WHERE ur.user_id = u.id
+			),
+			ARRAY(SELECT team_id::text FROM team_members WHERE user_id = u.id AND is_manager),
+			EXISTS(
+				SELECT 1 FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+				WHERE ur.user_id = u.id AND r.name = 'superadmin' AND r.built_in
+			),
+			EXISTS(SELECT 1 FROM user_totp WHERE user_id = u.id AND confirmed_at IS NOT NULL)`
+
+// scanDest returns the scan destinations matching principalColumns.
+func (p *Principal) scanDest() []any {
+	return []any{
+		&p.UserID,
+		&p.OrgID,
+		&p.Username,
+		scanTextArray(&p.Permissions),
+		scanUUIDArray(&p.ManagedTeamIDs),
+		&p.Superadmin,
+		&p.TOTPEnabled,
+	}
+}
+
+// Can reports whether the principal holds the permission.
+func (p *Principal) Can(permission string) bool {
+	return slices.Contains(p.Permissions, permission)
+}
+
+type Permission struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type Role struct {
+	ID          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	BuiltIn     bool      `json:"built_in"`
+	Permissions []string  `json:"permissions"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Built-in roles are shared by every organization, custom roles belong to one.
+const roleVisibleTo = `(org_id IS NULL OR org_id = $1)`
+
+type RoleModel struct {
+	DB     *sql.DB
+	config config.Config
+}
+
+// roleWriteError maps constraint violations of role writes to model errors.
+func roleWriteError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch {
+		case pgErr.Code == pgerrcode.UniqueViolation && pgErr.ConstraintName == "roles_org_id_name_key":
+			return ErrRoleNameConflict
+		case pgErr.Code == pgerrcode.ForeignKeyViolation && pgErr.ConstraintName == "role_permissions_permission_fkey":
+			return ErrUnknownPermission
+		}
+	}
+	return err
+}
+
+func setRolePermissions(ctx context.Context, tx *sql.Tx, roleID uuid.UUID, permissions []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM role_permissions WHERE role_id = $1`, roleID); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO role_permissions (role_id, permission)
+		SELECT $1, unnest($2::text[])
+	`
+	if _, err := tx.ExecContext(ctx, query, roleID, permissions); err != nil {
+		return roleWriteError(err)
+	}
+
+	return nil
+}
+
+// ------------------------------
+// Insert
+// ------------------------------
+// Insert creates a custom role of the organization.
+func (m *RoleModel) Insert(orgID uuid.UUID, role *Role) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO roles (org_id, name, description)
+		VALUES ($1, $2, $3)
+		RETURNING id, built_in, created_at
+	`
+	if err := tx.QueryRowContext(ctx, query, orgID, role.Name, role.Description).Scan(&role.ID, &role.BuiltIn, &role.CreatedAt); err != nil {
+		return roleWriteError(err)
+	}
+
+	if err := setRolePermissions(ctx, tx, role.ID, role.Permissions); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ------------------------------
+// Select
+// ------------------------------
+// GetAll returns the built-in roles and the custom roles of the organization.
+func (m *RoleModel) GetAll(orgID uuid.UUID) ([]Role, error) {
+	query := `
+		SELECT id, name, description, built_in,
+			ARRAY(SELECT permission FROM role_permissions WHERE role_id = roles.id ORDER BY permission),
+			created_at
+		FROM roles
+		WHERE ` + roleVisibleTo + `
+		ORDER BY built_in DESC, name
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := []Role{}
+	for rows.Next() {
+		var role Role
+		if err := rows.Scan(
+			&role.ID,
+			&role.Name,
+			&role.Description,
+			&role.BuiltIn,
+			scanTextArray(&role.Permissions),
+			&role.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return roles, nil
+}
+
+func (m *RoleModel) GetByID(orgID, id uuid.UUID) (*Role, error) {
+	query := `
+		SELECT id, name, description, built_in,
+			ARRAY(SELECT permission FROM role_permissions WHERE role_id = roles.id ORDER BY permission),
+			created_at
+		FROM roles
+		WHERE ` + roleVisibleTo + ` AND id = $2
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	var role Role
+	if err := m.DB.QueryRowContext(ctx, query, orgID, id).Scan(
+		&role.ID,
+		&role.Name,
+		&role.Description,
+		&role.BuiltIn,
+		scanTextArray(&role.Permissions),
+		&role.CreatedAt,
+	); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &role, nil
+}
+
+func (m *RoleModel) GetPermissions() ([]Permission, error) {
+	query := `
+		SELECT name, description
+		FROM permissions
+		ORDER BY name
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	permissions := []Permission{}
+	for rows.Next() {
+		var permission Permission
+		if err := rows.Scan(&permission.Name, &permission.Description); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, permission)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return permissions, nil
+}
+
+// ------------------------------
+// Update
+// ------------------------------
+
+// Update saves the name, description and permissions of a custom role of the
+// organization.
+func (m *RoleModel) Update(orgID uuid.UUID, role *Role) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var builtIn bool
+	if err := tx.QueryRowContext(ctx, `SELECT built_in FROM roles WHERE `+roleVisibleTo+` AND id = $2 FOR UPDATE`, orgID, role.ID).Scan(&builtIn); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+	if builtIn {
+		return ErrRoleBuiltIn
+	}
+
+	query := `
+		UPDATE roles
+		SET name = $1, description = $2
+		WHERE id = $3
+	`
+	if _, err := tx.ExecContext(ctx, query, role.Name, role.Description, role.ID); err != nil {
+		return roleWriteError(err)
+	}
+
+	if err := setRolePermissions(ctx, tx, role.ID, role.Permissions); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SetUserRoles replaces the roles of a user of the organization with roles
+// visible to it. It refuses with ErrLastSuperadmin to leave the organization
+// without a superadmin.
+func (m *RoleModel) SetUserRoles(orgID, userID uuid.UUID, roleIDs []uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_roles WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	ids := make([]string, len(roleIDs))
+	for i, id := range roleIDs {
+		ids[i] = id.String()
+	}
+
+	query := `
+		INSERT INTO user_roles (user_id, role_id)
+		SELECT u.id, r.id
+		FROM users u, roles r
+		WHERE ` + roleVisibleTo + ` AND u.org_id = $1 AND u.id = $2 AND r.id = ANY($3::uuid[])
+	`
+	result, err := tx.ExecContext(ctx, query, orgID, userID, ids)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected != int64(len(roleIDs)) {
+		return ErrUnknownRole
+	}
+
+	left, err := superadminLeft(ctx, tx, orgID)
+	if err != nil {
+		return err
+	}
+	if !left {
+		return ErrLastSuperadmin
+	}
+
+	return tx.Commit()
+}
+
+// superadminLeft reports whether the organization still has a superadmin who
+// can sign in, or will once they accept their invitation.
+func superadminLeft(ctx context.Context, tx *sql.Tx, orgID uuid.UUID) (bool, error) {
+	query := `
+		SELECT EXISTS(
+			SELECT 1
+			FROM user_roles ur
+			JOIN roles r ON r.id = ur.role_id
+			JOIN users u ON u.id = ur.user_id
+			WHERE r.name = 'superadmin' AND r.built_in AND u.org_id = $1 AND u.status IN ('invited', 'active')
+		)
+	`
+
+	var left bool
+	if err := tx.QueryRowContext(ctx, query, orgID).Scan(&left); err != nil {
+		return false, err
+	}
+
+	return left, nil
+}
+
+// AssignByName gives the user the named built-in role, keeping any others.
+func (m *RoleModel) AssignByName(userID uuid.UUID, name string) error {
+	query := `
+		INSERT INTO user_roles (user_id, role_id)
+		SELECT $1, id FROM roles WHERE name = $2 AND built_in
+		ON CONFLICT DO NOTHING
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, userID, name)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrUnknownRole
+	}
+
+	return nil
+}
+
+// ------------------------------
+// Delete
+// ------------------------------
+
+// Delete removes a custom role of the organization, taking it away from
+// everyone who held it.
+func (m *RoleModel) Delete(orgID, id uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	var builtIn bool
+	if err := m.DB.QueryRowContext(ctx, `SELECT built_in FROM roles WHERE `+roleVisibleTo+` AND id = $2`, orgID, id).Scan(&builtIn); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+	if builtIn {
+		return ErrRoleBuiltIn
+	}
+
+	result, err := m.DB.ExecContext(ctx, `DELETE FROM roles WHERE id = $1 AND org_id = $2`, id, orgID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
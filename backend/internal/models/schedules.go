@@ -0,0 +1,627 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jonathanhu237/when-works/backend/internal/config"
+)
+
+var (
+	ErrScheduleArchived = errors.New("schedule is archived")
+)
+
+type ScheduleStatus string
+
+const (
+	ScheduleDraft     ScheduleStatus = "draft"
+	SchedulePublished ScheduleStatus = "published"
+	ScheduleArchived  ScheduleStatus = "archived"
+)
+
+// Schedule groups the shifts that start within its period. Version is the
+// number of the latest published snapshot, or 0 if it was never published.
+type Schedule struct {
+	ID          uuid.UUID      `json:"id"`
+	OrgID       uuid.UUID      `json:"-"`
+	Title       string         `json:"title"`
+	PeriodStart time.Time      `json:"period_start"`
+	PeriodEnd   time.Time      `json:"period_end"`
+	Status      ScheduleStatus `json:"status"`
+	Version     int            `json:"version"`
+	// HasUnpublishedChanges is only filled in by GetByID.
+	HasUnpublishedChanges *bool     `json:"has_unpublished_changes,omitempty"`
+	CreatedAt             time.Time `json:"created_at"`
+	UpdatedAt             time.Time `json:"updated_at"`
+}
+
+// ScheduleEntry is one assignment as it was at publication time. The shift
+// details are copied so later shift edits do not rewrite history.
+type ScheduleEntry struct {
+	ShiftID uuid.UUID `json:"shift_id"`
+	UserID  uuid.UUID `json:"user_id"`
+	Title   string    `json:"title"`
+	StartAt time.Time `json:"start_at"`
+	EndAt   time.Time `json:"end_at"`
+}
+
+type ScheduleVersion struct {
+	ScheduleID  uuid.UUID       `json:"schedule_id"`
+	Version     int             `json:"version"`
+	Entries     []ScheduleEntry `json:"entries"`
+	PublishedBy *uuid.UUID      `json:"published_by"`
+	PublishedAt time.Time       `json:"published_at"`
+}
+
+type ScheduleChange struct {
+	Before ScheduleEntry `json:"before"`
+	After  ScheduleEntry `json:"after"`
+}
+
+type ScheduleDiff struct {
+	Added   []ScheduleEntry  `json:"added"`
+	Removed []ScheduleEntry  `json:"removed"`
+	Changed []ScheduleChange `json:"changed"`
+}
+
+type ScheduleModel struct {
+	DB     *sql.DB
+	config config.Config
+}
+
+// queryer is satisfied by both *sql.DB and *sql.Tx.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// ------------------------------
+// Insert
+// ------------------------------
+func (m *ScheduleModel) Insert(schedule *Schedule) error {
+	query := `
+		INSERT INTO schedules (org_id, title, period_start, period_end)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, status, version, created_at, updated_at
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	args := []any{schedule.OrgID, schedule.Title, schedule.PeriodStart, schedule.PeriodEnd}
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(
+		&schedule.ID,
+		&schedule.Status,
+		&schedule.Version,
+		&schedule.CreatedAt,
+		&schedule.UpdatedAt,
+	)
+}
+
+// ------------------------------
+// Select
+// ------------------------------
+func (m *ScheduleModel) GetByID(orgID, id uuid.UUID) (*Schedule, error) {
+	query := `
+		SELECT id, org_id, title, period_start, period_end, status, version, created_at, updated_at
+		FROM schedules
+		WHERE id = $1 AND org_id = $2
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	var schedule Schedule
+	if err := m.DB.QueryRowContext(ctx, query, id, orgID).Scan(
+		&schedule.ID,
+		&schedule.OrgID,
+		&schedule.Title,
+		&schedule.PeriodStart,
+		&schedule.PeriodEnd,
+		&schedule.Status,
+		&schedule.Version,
+		&schedule.CreatedAt,
+		&schedule.UpdatedAt,
+	); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	current, err := m.currentEntries(ctx, m.DB, schedule.OrgID, schedule.PeriodStart, schedule.PeriodEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	var published []ScheduleEntry
+	if schedule.Version > 0 {
+		version, err := m.getVersion(ctx, schedule.ID, schedule.Version)
+		if err != nil {
+			return nil, err
+		}
+		published = version.Entries
+	}
+
+	diff := DiffScheduleEntries(published, current)
+	changed := len(diff.Added)+len(diff.Removed)+len(diff.Changed) > 0
+	schedule.HasUnpublishedChanges = &changed
+
+	return &schedule, nil
+}
+
+func (m *ScheduleModel) GetAll(orgID uuid.UUID) ([]Schedule, error) {
+	query := `
+		SELECT id, org_id, title, period_start, period_end, status, version, created_at, updated_at
+		FROM schedules
+		WHERE org_id = $1
+		ORDER BY period_start DESC, id
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schedules := []Schedule{}
+
+	for rows.Next() {
+		var schedule Schedule
+
+		if err := rows.Scan(
+			&schedule.ID,
+			&schedule.OrgID,
+			&schedule.Title,
+			&schedule.PeriodStart,
+			&schedule.PeriodEnd,
+			&schedule.Status,
+			&schedule.Version,
+			&schedule.CreatedAt,
+			&schedule.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		schedules = append(schedules, schedule)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return schedules, nil
+}
+
+func (m *ScheduleModel) GetVersion(orgID, id uuid.UUID, version int) (*ScheduleVersion, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	if err := m.requireInOrg(ctx, orgID, id); err != nil {
+		return nil, err
+	}
+
+	return m.getVersion(ctx, id, version)
+}
+
+// requireInOrg returns ErrRecordNotFound unless the schedule belongs to the
+// organization.
+func (m *ScheduleModel) requireInOrg(ctx context.Context, orgID, id uuid.UUID) error {
+	var exists bool
+	if err := m.DB.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM schedules WHERE id = $1 AND org_id = $2)`, id, orgID).Scan(&exists); err != nil {
+		return err
+	}
+
+	if !exists {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (m *ScheduleModel) getVersion(ctx context.Context, id uuid.UUID, version int) (*ScheduleVersion, error) {
+	query := `
+		SELECT schedule_id, version, entries, published_by, published_at
+		FROM schedule_versions
+		WHERE schedule_id = $1 AND version = $2
+	`
+
+	var scheduleVersion ScheduleVersion
+	var entries []byte
+	if err := m.DB.QueryRowContext(ctx, query, id, version).Scan(
+		&scheduleVersion.ScheduleID,
+		&scheduleVersion.Version,
+		&entries,
+		&scheduleVersion.PublishedBy,
+		&scheduleVersion.PublishedAt,
+	); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	if err := json.Unmarshal(entries, &scheduleVersion.Entries); err != nil {
+		return nil, err
+	}
+
+	return &scheduleVersion, nil
+}
+
+// GetVersions lists the published versions of a schedule, newest first.
+func (m *ScheduleModel) GetVersions(orgID, id uuid.UUID) ([]ScheduleVersion, error) {
+	query := `
+		SELECT v.schedule_id, v.version, v.entries, v.published_by, v.published_at
+		FROM schedule_versions v
+		JOIN schedules s ON s.id = v.schedule_id
+		WHERE v.schedule_id = $1 AND s.org_id = $2
+		ORDER BY v.version DESC
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, id, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := []ScheduleVersion{}
+
+	for rows.Next() {
+		var version ScheduleVersion
+		var entries []byte
+
+		if err := rows.Scan(
+			&version.ScheduleID,
+			&version.Version,
+			&entries,
+			&version.PublishedBy,
+			&version.PublishedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(entries, &version.Entries); err != nil {
+			return nil, err
+		}
+
+		versions = append(versions, version)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+// currentEntries returns the live assignments of the organization's shifts
+// starting within [start, end) in a stable order.
+func (m *ScheduleModel) currentEntries(ctx context.Context, q queryer, orgID uuid.UUID, start, end time.Time) ([]ScheduleEntry, error) {
+	query := `
+		SELECT s.id, sa.user_id, s.title, s.start_at, s.end_at
+		FROM shifts s
+		JOIN shift_assignments sa ON sa.shift_id = s.id
+		WHERE s.org_id = $1 AND s.start_at >= $2 AND s.start_at < $3
+		ORDER BY s.start_at, s.id, sa.user_id
+	`
+
+	rows, err := q.QueryContext(ctx, query, orgID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []ScheduleEntry{}
+
+	for rows.Next() {
+		var entry ScheduleEntry
+
+		if err := rows.Scan(
+			&entry.ShiftID,
+			&entry.UserID,
+			&entry.Title,
+			&entry.StartAt,
+			&entry.EndAt,
+		); err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// ------------------------------
+// Lifecycle
+// ------------------------------
+
+// Publish snapshots the current assignments as the next version and marks the
+// schedule as published. It returns the new version together with the
+// previously published one, which is nil on the first publication.
+func (m *ScheduleModel) Publish(orgID, id uuid.UUID, publishedBy uuid.UUID) (*ScheduleVersion, *ScheduleVersion, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	schedule, err := m.lock(ctx, tx, orgID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if schedule.Status == ScheduleArchived {
+		return nil, nil, ErrScheduleArchived
+	}
+
+	var previous *ScheduleVersion
+	if schedule.Version > 0 {
+		previous, err = m.getVersion(ctx, schedule.ID, schedule.Version)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	entries, err := m.currentEntries(ctx, tx, schedule.OrgID, schedule.PeriodStart, schedule.PeriodEnd)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	encoded, err := json.Marshal(entries)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	version := ScheduleVersion{
+		ScheduleID:  schedule.ID,
+		Version:     schedule.Version + 1,
+		Entries:     entries,
+		PublishedBy: &publishedBy,
+	}
+
+	query := `
+		INSERT INTO schedule_versions (schedule_id, version, entries, published_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING published_at
+	`
+	args := []any{version.ScheduleID, version.Version, encoded, publishedBy}
+	if err := tx.QueryRowContext(ctx, query, args...).Scan(&version.PublishedAt); err != nil {
+		return nil, nil, err
+	}
+
+	query = `
+		UPDATE schedules
+		SET status = 'published', version = $1, updated_at = NOW()
+		WHERE id = $2
+	`
+	if _, err := tx.ExecContext(ctx, query, version.Version, schedule.ID); err != nil {
+		return nil, nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+
+	return &version, previous, nil
+}
+
+// Revert replaces the live assignments in the schedule's period with those of
+// the given version and puts the schedule back into draft, so the restored
+// state is only announced once it is published again as a new version.
+// Entries go through the same locked staffing checks as Assign. Those that no
+// longer fit, because their shift or user is gone, the user is inactive or the
+// entry would overfill, double-book or fall outside availability, are left
+// out and returned as conflicts.
+func (m *ScheduleModel) Revert(orgID, id uuid.UUID, version int) ([]AssignmentConflict, error) {
+	// Versions never change, so the target can be read ahead of the
+	// transaction, whose timeout grows with the number of entries
+	target, err := m.GetVersion(orgID, id, version)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := time.Duration(m.config.Database.QueryTimeout) * time.Second * time.Duration(len(target.Entries)+1)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	schedule, err := m.lock(ctx, tx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if schedule.Status == ScheduleArchived {
+		return nil, ErrScheduleArchived
+	}
+
+	query := `
+		DELETE FROM shift_assignments sa
+		USING shifts s
+		WHERE sa.shift_id = s.id AND s.org_id = $1 AND s.start_at >= $2 AND s.start_at < $3
+	`
+	if _, err := tx.ExecContext(ctx, query, schedule.OrgID, schedule.PeriodStart, schedule.PeriodEnd); err != nil {
+		return nil, err
+	}
+
+	shifts := ShiftModel{DB: m.DB, config: m.config}
+	conflicts := []AssignmentConflict{}
+	for _, entry := range target.Entries {
+		_, err := shifts.assign(ctx, tx, schedule.OrgID, entry.ShiftID, entry.UserID)
+		switch {
+		case err == nil:
+		case errors.Is(err, ErrRecordNotFound), isStaffingError(err):
+			conflicts = append(conflicts, AssignmentConflict{ShiftID: entry.ShiftID, UserID: entry.UserID, Err: err})
+		default:
+			return nil, err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE schedules SET status = 'draft', updated_at = NOW() WHERE id = $1`, schedule.ID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return conflicts, nil
+}
+
+func (m *ScheduleModel) Archive(orgID, id uuid.UUID) error {
+	query := `
+		UPDATE schedules
+		SET status = 'archived', updated_at = NOW()
+		WHERE id = $1 AND org_id = $2
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, orgID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (m *ScheduleModel) lock(ctx context.Context, tx *sql.Tx, orgID, id uuid.UUID) (*Schedule, error) {
+	query := `
+		SELECT id, org_id, title, period_start, period_end, status, version, created_at, updated_at
+		FROM schedules
+		WHERE id = $1 AND org_id = $2
+		FOR UPDATE
+	`
+
+	var schedule Schedule
+	if err := tx.QueryRowContext(ctx, query, id, orgID).Scan(
+		&schedule.ID,
+		&schedule.OrgID,
+		&schedule.Title,
+		&schedule.PeriodStart,
+		&schedule.PeriodEnd,
+		&schedule.Status,
+		&schedule.Version,
+		&schedule.CreatedAt,
+		&schedule.UpdatedAt,
+	); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &schedule, nil
+}
+
+// ------------------------------
+// Diff
+// ------------------------------
+
+// DiffScheduleEntries compares two snapshots by (shift, user). An entry whose
+// shift kept its assignee but moved in time or was renamed counts as changed.
+func DiffScheduleEntries(before, after []ScheduleEntry) ScheduleDiff {
+	type key struct {
+		shiftID, userID uuid.UUID
+	}
+
+	diff := ScheduleDiff{
+		Added:   []ScheduleEntry{},
+		Removed: []ScheduleEntry{},
+		Changed: []ScheduleChange{},
+	}
+
+	previous := make(map[key]ScheduleEntry, len(before))
+	for _, entry := range before {
+		previous[key{entry.ShiftID, entry.UserID}] = entry
+	}
+
+	seen := make(map[key]bool, len(after))
+	for _, entry := range after {
+		k := key{entry.ShiftID, entry.UserID}
+		seen[k] = true
+
+		old, ok := previous[k]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, entry)
+		case !old.StartAt.Equal(entry.StartAt) || !old.EndAt.Equal(entry.EndAt) || old.Title != entry.Title:
+			diff.Changed = append(diff.Changed, ScheduleChange{Before: old, After: entry})
+		}
+	}
+
+	for _, entry := range before {
+		if !seen[key{entry.ShiftID, entry.UserID}] {
+			diff.Removed = append(diff.Removed, entry)
+		}
+	}
+
+	return diff
+}
+
+// AffectedUserIDs lists, in a stable order, everyone who appears in the diff.
+func (d ScheduleDiff) AffectedUserIDs() []uuid.UUID {
+	seen := make(map[uuid.UUID]bool)
+	var userIDs []uuid.UUID
+
+	add := func(userID uuid.UUID) {
+		if !seen[userID] {
+			seen[userID] = true
+			userIDs = append(userIDs, userID)
+		}
+	}
+
+	for _, entry := range d.Added {
+		add(entry.UserID)
+	}
+	for _, entry := range d.Removed {
+		add(entry.UserID)
+	}
+	for _, change := range d.Changed {
+		add(change.After.UserID)
+	}
+
+	slices.SortFunc(userIDs, func(a, b uuid.UUID) int {
+		return slices.Compare(a[:], b[:])
+	})
+
+	return userIDs
+}
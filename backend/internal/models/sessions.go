@@ -0,0 +1,343 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jonathanhu237/when-works/backend/internal/config"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token has already been used")
+)
+
+// Session is one login. Its refresh tokens form a family: each refresh uses
+// up the current token and issues the next one, and presenting a used token
+// again revokes the whole session.
+type Session struct {
+	ID         uuid.UUID  `json:"id"`
+	UserID     uuid.UUID  `json:"user_id"`
+	UserAgent  string     `json:"user_agent"`
+	IP         string     `json:"ip"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	// Current marks the session the request was made with.
+	Current bool `json:"current"`
+}
+
+type SessionModel struct {
+	DB     *sql.DB
+	config config.Config
+}
+
+// ------------------------------
+// Insert
+// ------------------------------
+
+// Create starts a session for the user and returns it with its first refresh
+// token.
+func (m *SessionModel) Create(userID uuid.UUID, userAgent, ip string) (*Session, string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer tx.Rollback()
+
+	session := Session{UserID: userID, UserAgent: userAgent, IP: ip}
+	query := `
+		INSERT INTO sessions (user_id, user_agent, ip)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at, last_seen_at
+	`
+	if err := tx.QueryRowContext(ctx, query, userID, userAgent, ip).Scan(&session.ID, &session.CreatedAt, &session.LastSeenAt); err != nil {
+		return nil, "", err
+	}
+
+	token, err := m.insertRefreshToken(ctx, tx, session.ID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, "", err
+	}
+
+	return &session, token, nil
+}
+
+func (m *SessionModel) insertRefreshToken(ctx context.Context, tx *sql.Tx, sessionID uuid.UUID) (string, error) {
+	token, tokenHash, err := generateToken()
+	if err != nil {
+		return "", err
+	}
+
+	expiresAt := time.Now().Add(time.Duration(m.config.JWT.RefreshExpiration) * time.Second)
+	query := `
+		INSERT INTO refresh_tokens (session_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)
+	`
+	if _, err := tx.ExecContext(ctx, query, sessionID, tokenHash, expiresAt); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// ------------------------------
+// Select
+// ------------------------------
+
+// GetActiveForUser returns the user's sessions that are neither revoked nor
+// expired, most recently used first.
+func (m *SessionModel) GetActiveForUser(userID uuid.UUID) ([]Session, error) {
+	query := `
+		SELECT s.id, s.user_id, s.user_agent, s.ip, s.created_at, s.last_seen_at, s.revoked_at
+		FROM sessions s
+		WHERE s.user_id = $1 AND s.revoked_at IS NULL
+			AND EXISTS (
+				SELECT 1 FROM refresh_tokens t
+				WHERE t.session_id = s.id AND t.used_at IS NULL AND t.expires_at > NOW()
+			)
+		ORDER BY s.last_seen_at DESC
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []Session{}
+
+	for rows.Next() {
+		var session Session
+		if err := rows.Scan(
+			&session.ID,
+			&session.UserID,
+			&session.UserAgent,
+			&session.IP,
+			&session.CreatedAt,
+			&session.LastSeenAt,
+			&session.RevokedAt,
+		); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// ------------------------------
+// Touch
+// ------------------------------
+
+// Touch records activity on the session and returns its user with their
+// current permissions, managed teams, superadmin role and two-factor
+// enrollment, in a single statement so every authenticated request costs one
+// round trip. Reading them here rather than trusting the access
+// token makes role changes and deletions apply to the very next request. A
+// revoked or missing session yields ErrRecordNotFound.
+func (m *SessionModel) Touch(id uuid.UUID) (*Principal, error) {
+	query := `
+		UPDATE sessions s
+		SET last_seen_at = NOW()
+		FROM users u
+		WHERE s.id = $1 AND s.revoked_at IS NULL AND u.id = s.user_id AND u.status = 'active'
+		RETURNING ` + principalColumns
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	var principal Principal
+	if err := m.DB.QueryRowContext(ctx, query, id).Scan(principal.scanDest()...); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &principal, nil
+}
+
+// ------------------------------
+// Rotate
+// ------------------------------
+
+// Rotate uses up the refresh token and returns its session with the next
+// token. If the token was already used, someone is replaying it: the session
+// is revoked and ErrRefreshTokenReused returned.
+func (m *SessionModel) Rotate(token string) (*Session, string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer tx.Rollback()
+
+	var session Session
+	var tokenID uuid.UUID
+	var expiresAt time.Time
+	var usedAt *time.Time
+
+	query := `
+		SELECT s.id, s.user_id, s.user_agent, s.ip, s.created_at, s.last_seen_at, s.revoked_at, t.id, t.expires_at, t.used_at
+		FROM refresh_tokens t
+		JOIN sessions s ON s.id = t.session_id
+		WHERE t.token_hash = $1
+		FOR UPDATE
+	`
+	if err := tx.QueryRowContext(ctx, query, hashToken(token)).Scan(
+		&session.ID,
+		&session.UserID,
+		&session.UserAgent,
+		&session.IP,
+		&session.CreatedAt,
+		&session.LastSeenAt,
+		&session.RevokedAt,
+		&tokenID,
+		&expiresAt,
+		&usedAt,
+	); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, "", ErrInvalidRefreshToken
+		default:
+			return nil, "", err
+		}
+	}
+
+	if session.RevokedAt != nil {
+		return nil, "", ErrInvalidRefreshToken
+	}
+
+	if usedAt != nil {
+		if _, err := tx.ExecContext(ctx, `UPDATE sessions SET revoked_at = NOW() WHERE id = $1`, session.ID); err != nil {
+			return nil, "", err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, "", err
+		}
+		return nil, "", ErrRefreshTokenReused
+	}
+
+	if !time.Now().Before(expiresAt) {
+		return nil, "", ErrInvalidRefreshToken
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE refresh_tokens SET used_at = NOW() WHERE id = $1`, tokenID); err != nil {
+		return nil, "", err
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE sessions SET last_seen_at = NOW() WHERE id = $1`, session.ID); err != nil {
+		return nil, "", err
+	}
+
+	next, err := m.insertRefreshToken(ctx, tx, session.ID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, "", err
+	}
+
+	return &session, next, nil
+}
+
+// ------------------------------
+// Revoke
+// ------------------------------
+func (m *SessionModel) Revoke(id uuid.UUID) error {
+	query := `
+		UPDATE sessions
+		SET revoked_at = NOW()
+		WHERE id = $1 AND revoked_at IS NULL
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, id)
+	return err
+}
+
+// RevokeForUser revokes one of the user's sessions. Sessions of other users
+// and sessions that are already revoked are reported as not found.
+func (m *SessionModel) RevokeForUser(id, userID uuid.UUID) error {
+	query := `
+		UPDATE sessions
+		SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// RevokeAllForUser revokes every session of the user except keep, which may
+// be uuid.Nil to revoke them all.
+func (m *SessionModel) RevokeAllForUser(userID, keep uuid.UUID) error {
+	query := `
+		UPDATE sessions
+		SET revoked_at = NOW()
+		WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, userID, keep)
+	return err
+}
+
+// RevokeByRefreshToken revokes the session the refresh token belongs to,
+// whether or not the token is still usable.
+func (m *SessionModel) RevokeByRefreshToken(token string) error {
+	query := `
+		UPDATE sessions
+		SET revoked_at = NOW()
+		WHERE revoked_at IS NULL AND id = (SELECT session_id FROM refresh_tokens WHERE token_hash = $1)
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, hashToken(token))
+	return err
+}
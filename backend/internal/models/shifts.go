@@ -0,0 +1,679 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jonathanhu237/when-works/backend/internal/config"
+)
+
+var (
+	ErrShiftFull           = errors.New("shift is already fully staffed")
+	ErrAlreadyAssigned     = errors.New("user is already assigned to this shift")
+	ErrDoubleBooking       = errors.New("user is already assigned to an overlapping shift")
+	ErrOutsideAvailability = errors.New("shift is outside the user's availability")
+	ErrUserInactive        = errors.New("user is deactivated or archived")
+)
+
+// AssignmentConflict is an assignment that breaks a staffing rule. Err is one
+// of the errors of Assign.
+type AssignmentConflict struct {
+	ShiftID uuid.UUID
+	UserID  uuid.UUID
+	Err     error
+}
+
+// AssignmentConflictError is returned by changes that would leave existing
+// assignments breaking the staffing rules. Nothing is written.
+type AssignmentConflictError struct {
+	Conflicts []AssignmentConflict
+}
+
+func (e *AssignmentConflictError) Error() string {
+	return fmt.Sprintf("%d assignments would break the staffing rules", len(e.Conflicts))
+}
+
+// isStaffingError reports whether err is one of the staffing rule errors of
+// Assign.
+func isStaffingError(err error) bool {
+	for _, target := range []error{ErrShiftFull, ErrAlreadyAssigned, ErrDoubleBooking, ErrOutsideAvailability, ErrUserInactive} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+type Shift struct {
+	ID                uuid.UUID         `json:"id"`
+	OrgID             uuid.UUID         `json:"-"`
+	Title             string            `json:"title"`
+	StartAt           time.Time         `json:"start_at"`
+	EndAt             time.Time         `json:"end_at"`
+	RequiredHeadcount int               `json:"required_headcount"`
+	Tag               *string           `json:"tag"`
+	Assignments       []ShiftAssignment `json:"assignments"`
+	CreatedAt         time.Time         `json:"created_at"`
+	UpdatedAt         time.Time         `json:"updated_at"`
+}
+
+type ShiftAssignment struct {
+	ID        uuid.UUID `json:"id"`
+	ShiftID   uuid.UUID `json:"shift_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ShiftModel struct {
+	DB     *sql.DB
+	config config.Config
+}
+
+// ------------------------------
+// Insert
+// ------------------------------
+func (m *ShiftModel) Insert(shift *Shift) error {
+	query := `
+		INSERT INTO shifts (org_id, title, start_at, end_at, required_headcount, tag)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	args := []any{shift.OrgID, shift.Title, shift.StartAt, shift.EndAt, shift.RequiredHeadcount, shift.Tag}
+	if err := m.DB.QueryRowContext(ctx, query, args...).Scan(&shift.ID, &shift.CreatedAt, &shift.UpdatedAt); err != nil {
+		return err
+	}
+
+	shift.Assignments = []ShiftAssignment{}
+	return nil
+}
+
+// ------------------------------
+// Select
+// ------------------------------
+func (m *ShiftModel) GetByID(orgID, id uuid.UUID) (*Shift, error) {
+	return m.GetInScope(AllUsers(orgID).WithArchived(), id)
+}
+
+// GetInScope returns a shift of the scope's organization with only the
+// assignments of users within the scope.
+func (m *ShiftModel) GetInScope(scope UserScope, id uuid.UUID) (*Shift, error) {
+	query := `
+		SELECT id, org_id, title, start_at, end_at, required_headcount, tag, created_at, updated_at
+		FROM shifts
+		WHERE id = $1 AND org_id = $2
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	var shift Shift
+	if err := m.DB.QueryRowContext(ctx, query, id, scope.orgID).Scan(
+		&shift.ID,
+		&shift.OrgID,
+		&shift.Title,
+		&shift.StartAt,
+		&shift.EndAt,
+		&shift.RequiredHeadcount,
+		&shift.Tag,
+		&shift.CreatedAt,
+		&shift.UpdatedAt,
+	); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	shifts := []Shift{shift}
+	if err := m.loadAssignments(ctx, &scope, shifts); err != nil {
+		return nil, err
+	}
+
+	return &shifts[0], nil
+}
+
+// GetAll returns every shift of the scope's organization that overlaps
+// [from, to), ordered by start time, with only the assignments of users
+// within the scope.
+func (m *ShiftModel) GetAll(scope UserScope, from, to time.Time) ([]Shift, error) {
+	query := `
+		SELECT id, org_id, title, start_at, end_at, required_headcount, tag, created_at, updated_at
+		FROM shifts
+		WHERE org_id = $1 AND start_at < $3 AND end_at > $2
+		ORDER BY start_at, id
+	`
+
+	return m.queryShifts(&scope, query, scope.orgID, from, to)
+}
+
+// GetForUser returns the shifts overlapping [from, to) that the user works
+// according to the latest published version of each schedule, as employees
+// see them. Title and times are those published; unpublished changes and
+// shifts outside any published schedule are left out. A shift in several
+// schedules is taken from the most recent publication. The assignments are
+// the published co-workers and carry no ID, and UpdatedAt is the time of
+// publication.
+func (m *ShiftModel) GetForUser(userID uuid.UUID, from, to time.Time) ([]Shift, error) {
+	query := `
+		WITH published AS (
+			SELECT DISTINCT ON (e.shift_id)
+				e.shift_id, sc.org_id, e.title, e.start_at, e.end_at, v.entries, v.published_at
+			FROM schedules sc
+			JOIN schedule_versions v ON v.schedule_id = sc.id AND v.version = sc.version
+			CROSS JOIN LATERAL jsonb_to_recordset(v.entries)
+				AS e(shift_id uuid, user_id uuid, title text, start_at timestamptz, end_at timestamptz)
+			WHERE sc.org_id = (SELECT org_id FROM users WHERE id = $1)
+			AND e.user_id = $1 AND e.start_at < $3 AND e.end_at > $2
+			ORDER BY e.shift_id, v.published_at DESC
+		)
+		SELECT p.shift_id, p.org_id, p.title, p.start_at, p.end_at,
+			COALESCE(s.required_headcount, 0), s.tag, COALESCE(s.created_at, p.published_at), p.published_at,
+			ARRAY(
+				SELECT x->>'user_id' FROM jsonb_array_elements(p.entries) x
+				WHERE x->>'shift_id' = p.shift_id::text
+				ORDER BY x->>'user_id'
+			)
+		FROM published p
+		LEFT JOIN shifts s ON s.id = p.shift_id
+		ORDER BY p.start_at, p.shift_id
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shifts := []Shift{}
+
+	for rows.Next() {
+		var shift Shift
+		var userIDs []uuid.UUID
+
+		if err := rows.Scan(
+			&shift.ID,
+			&shift.OrgID,
+			&shift.Title,
+			&shift.StartAt,
+			&shift.EndAt,
+			&shift.RequiredHeadcount,
+			&shift.Tag,
+			&shift.CreatedAt,
+			&shift.UpdatedAt,
+			scanUUIDArray(&userIDs),
+		); err != nil {
+			return nil, err
+		}
+
+		shift.Assignments = make([]ShiftAssignment, len(userIDs))
+		for i, assignee := range userIDs {
+			shift.Assignments[i] = ShiftAssignment{ShiftID: shift.ID, UserID: assignee, CreatedAt: shift.UpdatedAt}
+		}
+
+		shifts = append(shifts, shift)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return shifts, nil
+}
+
+// GetLiveForUser returns the shifts overlapping [from, to) that the user is
+// assigned to right now, including changes not published yet.
+func (m *ShiftModel) GetLiveForUser(userID uuid.UUID, from, to time.Time) ([]Shift, error) {
+	query := `
+		SELECT s.id, s.org_id, s.title, s.start_at, s.end_at, s.required_headcount, s.tag, s.created_at, s.updated_at
+		FROM shifts s
+		JOIN shift_assignments sa ON sa.shift_id = s.id
+		WHERE sa.user_id = $1 AND s.start_at < $3 AND s.end_at > $2
+		ORDER BY s.start_at, s.id
+	`
+
+	return m.queryShifts(nil, query, userID, from, to)
+}
+
+func (m *ShiftModel) queryShifts(scope *UserScope, query string, args ...any) ([]Shift, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shifts := []Shift{}
+
+	for rows.Next() {
+		var shift Shift
+
+		if err := rows.Scan(
+			&shift.ID,
+			&shift.OrgID,
+			&shift.Title,
+			&shift.StartAt,
+			&shift.EndAt,
+			&shift.RequiredHeadcount,
+			&shift.Tag,
+			&shift.CreatedAt,
+			&shift.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		shifts = append(shifts, shift)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := m.loadAssignments(ctx, scope, shifts); err != nil {
+		return nil, err
+	}
+
+	return shifts, nil
+}
+
+// loadAssignments fills in the Assignments of every shift with one query,
+// leaving out users outside the scope if one is given.
+func (m *ShiftModel) loadAssignments(ctx context.Context, scope *UserScope, shifts []Shift) error {
+	if len(shifts) == 0 {
+		return nil
+	}
+
+	index := make(map[uuid.UUID]int, len(shifts))
+	ids := make([]string, len(shifts))
+	for i := range shifts {
+		shifts[i].Assignments = []ShiftAssignment{}
+		index[shifts[i].ID] = i
+		ids[i] = shifts[i].ID.String()
+	}
+
+	query := `
+		SELECT id, shift_id, user_id, created_at
+		FROM shift_assignments
+		WHERE shift_id = ANY($1::uuid[])
+		ORDER BY created_at, id
+	`
+	args := []any{ids}
+	if scope != nil {
+		query = `
+			SELECT id, shift_id, user_id, created_at
+			FROM shift_assignments
+			WHERE shift_id = ANY($5::uuid[])
+			AND user_id IN (SELECT id FROM users WHERE ` + scopeCondition + `)
+			ORDER BY created_at, id
+		`
+		args = append(scope.args(), ids)
+	}
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var assignment ShiftAssignment
+
+		if err := rows.Scan(
+			&assignment.ID,
+			&assignment.ShiftID,
+			&assignment.UserID,
+			&assignment.CreatedAt,
+		); err != nil {
+			return err
+		}
+
+		i := index[assignment.ShiftID]
+		shifts[i].Assignments = append(shifts[i].Assignments, assignment)
+	}
+
+	return rows.Err()
+}
+
+// ------------------------------
+// Update
+// ------------------------------
+
+// Update saves the shift. The shift and its assignees are locked, and if the
+// shift moves in time or needs fewer people, the existing assignments are
+// checked again: assignees beyond the new head-count, double-booked by the
+// new times or no longer available yield an *AssignmentConflictError.
+func (m *ShiftModel) Update(shift *Shift) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var before Shift
+	query := `SELECT start_at, end_at, required_headcount FROM shifts WHERE id = $1 AND org_id = $2 FOR UPDATE`
+	if err := tx.QueryRowContext(ctx, query, shift.ID, shift.OrgID).Scan(&before.StartAt, &before.EndAt, &before.RequiredHeadcount); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	moved := !before.StartAt.Equal(shift.StartAt) || !before.EndAt.Equal(shift.EndAt)
+	if moved || shift.RequiredHeadcount < before.RequiredHeadcount {
+		conflicts, err := m.recheckAssignments(ctx, tx, shift, moved)
+		if err != nil {
+			return err
+		}
+		if len(conflicts) > 0 {
+			return &AssignmentConflictError{Conflicts: conflicts}
+		}
+	}
+
+	query = `
+		UPDATE shifts
+		SET title = $1, start_at = $2, end_at = $3, required_headcount = $4, tag = $5, updated_at = NOW()
+		WHERE id = $6
+		RETURNING updated_at
+	`
+	args := []any{shift.Title, shift.StartAt, shift.EndAt, shift.RequiredHeadcount, shift.Tag, shift.ID}
+	if err := tx.QueryRowContext(ctx, query, args...).Scan(&shift.UpdatedAt); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// recheckAssignments locks the assignees of the shift and returns those who
+// would break the staffing rules with its new head-count, and if it moved,
+// with its new times. Assignees beyond the head-count are the latest ones.
+func (m *ShiftModel) recheckAssignments(ctx context.Context, tx *sql.Tx, shift *Shift, moved bool) ([]AssignmentConflict, error) {
+	query := `
+		SELECT sa.user_id
+		FROM shift_assignments sa
+		JOIN users u ON u.id = sa.user_id
+		WHERE sa.shift_id = $1
+		ORDER BY sa.created_at, sa.id
+		FOR UPDATE
+	`
+
+	rows, err := tx.QueryContext(ctx, query, shift.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []uuid.UUID
+	for rows.Next() {
+		var userID uuid.UUID
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	var conflicts []AssignmentConflict
+	for i, userID := range userIDs {
+		if i >= shift.RequiredHeadcount {
+			conflicts = append(conflicts, AssignmentConflict{ShiftID: shift.ID, UserID: userID, Err: ErrShiftFull})
+			continue
+		}
+		if !moved {
+			continue
+		}
+
+		var doubleBooked bool
+		query := `
+			SELECT EXISTS(
+				SELECT 1
+				FROM shift_assignments sa
+				JOIN shifts s ON s.id = sa.shift_id
+				WHERE sa.user_id = $1 AND s.id <> $2 AND s.start_at < $4 AND s.end_at > $3
+			)
+		`
+		if err := tx.QueryRowContext(ctx, query, userID, shift.ID, shift.StartAt, shift.EndAt).Scan(&doubleBooked); err != nil {
+			return nil, err
+		}
+		if doubleBooked {
+			conflicts = append(conflicts, AssignmentConflict{ShiftID: shift.ID, UserID: userID, Err: ErrDoubleBooking})
+			continue
+		}
+
+		available, err := isAvailable(ctx, tx, userID, shift.StartAt, shift.EndAt)
+		if err != nil {
+			return nil, err
+		}
+		if !available {
+			conflicts = append(conflicts, AssignmentConflict{ShiftID: shift.ID, UserID: userID, Err: ErrOutsideAvailability})
+		}
+	}
+
+	return conflicts, nil
+}
+
+// ------------------------------
+// Delete
+// ------------------------------
+func (m *ShiftModel) Delete(orgID, id uuid.UUID) error {
+	query := `
+		DELETE FROM shifts
+		WHERE id = $1 AND org_id = $2
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, orgID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// ------------------------------
+// Assignments
+// ------------------------------
+
+// Assign adds the user to the shift, both of which must belong to the
+// organization. It fails with ErrShiftFull, ErrAlreadyAssigned,
+// ErrDoubleBooking, ErrOutsideAvailability or ErrUserInactive when the
+// assignment would break one of the staffing rules.
+func (m *ShiftModel) Assign(orgID, shiftID, userID uuid.UUID) (*ShiftAssignment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	assignment, err := m.assign(ctx, tx, orgID, shiftID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return assignment, nil
+}
+
+// AssignMany applies several assignments atomically: either all of them pass
+// the staffing checks and are stored, or none are. The IDs and creation times
+// of the given assignments are filled in on success.
+func (m *ShiftModel) AssignMany(orgID uuid.UUID, assignments []ShiftAssignment) error {
+	timeout := time.Duration(m.config.Database.QueryTimeout) * time.Second * time.Duration(len(assignments)+1)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for i := range assignments {
+		assignment, err := m.assign(ctx, tx, orgID, assignments[i].ShiftID, assignments[i].UserID)
+		if err != nil {
+			return fmt.Errorf("assigning user %s to shift %s: %w", assignments[i].UserID, assignments[i].ShiftID, err)
+		}
+		assignments[i] = *assignment
+	}
+
+	return tx.Commit()
+}
+
+// assign runs the staffing checks and inserts the assignment inside tx. The
+// shift and user rows are locked so that concurrent assignments cannot both
+// pass the head-count or double-booking checks.
+func (m *ShiftModel) assign(ctx context.Context, tx *sql.Tx, orgID, shiftID, userID uuid.UUID) (*ShiftAssignment, error) {
+	var shift Shift
+	query := `SELECT id, start_at, end_at, required_headcount FROM shifts WHERE id = $1 AND org_id = $2 FOR UPDATE`
+	if err := tx.QueryRowContext(ctx, query, shiftID, orgID).Scan(&shift.ID, &shift.StartAt, &shift.EndAt, &shift.RequiredHeadcount); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	var status string
+	if err := tx.QueryRowContext(ctx, `SELECT status FROM users WHERE id = $1 AND org_id = $2 FOR UPDATE`, userID, orgID).Scan(&status); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	if status != UserStatusInvited && status != UserStatusActive {
+		return nil, ErrUserInactive
+	}
+
+	var assigned int
+	var alreadyAssigned, doubleBooked bool
+	query = `
+		SELECT
+			(SELECT COUNT(*) FROM shift_assignments WHERE shift_id = $1),
+			EXISTS(SELECT 1 FROM shift_assignments WHERE shift_id = $1 AND user_id = $2),
+			EXISTS(
+				SELECT 1
+				FROM shift_assignments sa
+				JOIN shifts s ON s.id = sa.shift_id
+				WHERE sa.user_id = $2 AND s.id <> $1 AND s.start_at < $4 AND s.end_at > $3
+			)
+	`
+	args := []any{shift.ID, userID, shift.StartAt, shift.EndAt}
+	if err := tx.QueryRowContext(ctx, query, args...).Scan(&assigned, &alreadyAssigned, &doubleBooked); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case alreadyAssigned:
+		return nil, ErrAlreadyAssigned
+	case assigned >= shift.RequiredHeadcount:
+		return nil, ErrShiftFull
+	case doubleBooked:
+		return nil, ErrDoubleBooking
+	}
+
+	available, err := isAvailable(ctx, tx, userID, shift.StartAt, shift.EndAt)
+	if err != nil {
+		return nil, err
+	}
+	if !available {
+		return nil, ErrOutsideAvailability
+	}
+
+	assignment := ShiftAssignment{ShiftID: shift.ID, UserID: userID}
+	query = `
+		INSERT INTO shift_assignments (shift_id, user_id)
+		VALUES ($1, $2)
+		RETURNING id, created_at
+	`
+	if err := tx.QueryRowContext(ctx, query, shift.ID, userID).Scan(&assignment.ID, &assignment.CreatedAt); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return nil, ErrAlreadyAssigned
+		}
+		return nil, err
+	}
+
+	return &assignment, nil
+}
+
+// isAvailable reports whether the user's resolved availability covers the
+// whole of [start, end), reading it within tx.
+func isAvailable(ctx context.Context, tx *sql.Tx, userID uuid.UUID, start, end time.Time) (bool, error) {
+	intervals, err := resolveAvailability(ctx, tx, userID, start, end)
+	if err != nil {
+		return false, err
+	}
+
+	return covered(intervals, start, end), nil
+}
+
+func (m *ShiftModel) Unassign(orgID, shiftID, userID uuid.UUID) error {
+	query := `
+		DELETE FROM shift_assignments sa
+		USING shifts s
+		WHERE sa.shift_id = $1 AND sa.user_id = $2 AND s.id = sa.shift_id AND s.org_id = $3
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, shiftID, userID, orgID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
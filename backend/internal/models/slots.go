@@ -0,0 +1,67 @@
+package models
+
+import (
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Slot is a candidate meeting time together with the users who are free for
+// all of it.
+type Slot struct {
+	Start            time.Time   `json:"start"`
+	End              time.Time   `json:"end"`
+	AvailableCount   int         `json:"available_count"`
+	AvailableUserIDs []uuid.UUID `json:"available_user_ids"`
+}
+
+// FindSlots walks [from, to) in steps of granularity and, for every start
+// time, counts the users whose availability covers the whole duration. Slots
+// nobody can attend are dropped. The result is ranked by attendance (most
+// first), then by start time, and truncated to limit.
+func FindSlots(availability map[uuid.UUID][]Interval, userIDs []uuid.UUID, from, to time.Time, duration, granularity time.Duration, limit int) []Slot {
+	var slots []Slot
+
+	for start := from; !start.Add(duration).After(to); start = start.Add(granularity) {
+		end := start.Add(duration)
+
+		slot := Slot{Start: start, End: end, AvailableUserIDs: []uuid.UUID{}}
+		for _, userID := range userIDs {
+			if covered(availability[userID], start, end) {
+				slot.AvailableUserIDs = append(slot.AvailableUserIDs, userID)
+			}
+		}
+
+		slot.AvailableCount = len(slot.AvailableUserIDs)
+		if slot.AvailableCount > 0 {
+			slots = append(slots, slot)
+		}
+	}
+
+	slices.SortStableFunc(slots, func(a, b Slot) int {
+		if a.AvailableCount != b.AvailableCount {
+			return b.AvailableCount - a.AvailableCount
+		}
+		return a.Start.Compare(b.Start)
+	})
+
+	if len(slots) > limit {
+		slots = slots[:limit]
+	}
+
+	return slots
+}
+
+// covered reports whether one of the merged, sorted intervals contains
+// [start, end).
+func covered(intervals []Interval, start, end time.Time) bool {
+	i, _ := slices.BinarySearchFunc(intervals, start, func(interval Interval, t time.Time) int {
+		if interval.End.After(t) {
+			return 1
+		}
+		return -1
+	})
+
+	return i < len(intervals) && intervals[i].Covers(start, end)
+}
@@ -0,0 +1,455 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jonathanhu237/when-works/backend/internal/config"
+)
+
+var (
+	ErrNotAssigned       = errors.New("user is not assigned to the shift")
+	ErrSwapInvalidStatus = errors.New("swap is not in a state that allows this transition")
+	ErrSwapNotAllowed    = errors.New("user is not allowed to act on this swap")
+)
+
+type SwapKind string
+
+const (
+	SwapGiveaway SwapKind = "giveaway"
+	SwapExchange SwapKind = "swap"
+)
+
+type SwapStatus string
+
+const (
+	SwapOpen      SwapStatus = "open"
+	SwapAccepted  SwapStatus = "accepted"
+	SwapApproved  SwapStatus = "approved"
+	SwapRejected  SwapStatus = "rejected"
+	SwapCancelled SwapStatus = "cancelled"
+)
+
+// Swap is an offer by the requester to hand over ShiftID. A giveaway can be
+// taken by any colleague (or only TargetUserID when set); an exchange trades
+// it for TargetShiftID, which TargetUserID currently works.
+type Swap struct {
+	ID            uuid.UUID   `json:"id"`
+	Kind          SwapKind    `json:"kind"`
+	Status        SwapStatus  `json:"status"`
+	ShiftID       uuid.UUID   `json:"shift_id"`
+	RequesterID   uuid.UUID   `json:"requester_id"`
+	TargetShiftID *uuid.UUID  `json:"target_shift_id"`
+	TargetUserID  *uuid.UUID  `json:"target_user_id"`
+	AccepterID    *uuid.UUID  `json:"accepter_id"`
+	Note          string      `json:"note"`
+	Events        []SwapEvent `json:"events,omitempty"`
+	CreatedAt     time.Time   `json:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at"`
+}
+
+// SwapEvent records one status transition of a swap.
+type SwapEvent struct {
+	Status    SwapStatus `json:"status"`
+	ActorID   *uuid.UUID `json:"actor_id"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Parties returns everyone involved in the swap.
+func (s *Swap) Parties() []uuid.UUID {
+	parties := []uuid.UUID{s.RequesterID}
+	if s.TargetUserID != nil {
+		parties = append(parties, *s.TargetUserID)
+	}
+	if s.AccepterID != nil && (s.TargetUserID == nil || *s.AccepterID != *s.TargetUserID) {
+		parties = append(parties, *s.AccepterID)
+	}
+	return parties
+}
+
+type SwapModel struct {
+	DB     *sql.DB
+	config config.Config
+}
+
+const swapColumns = `
+	id, kind, status, shift_id, requester_id, target_shift_id, target_user_id, accepter_id, note, created_at, updated_at
+`
+
+// A swap belongs to the organization of its shift.
+const swapInOrg = `shift_id IN (SELECT id FROM shifts WHERE org_id = $1)`
+
+func scanSwap(row interface{ Scan(...any) error }, swap *Swap) error {
+	return row.Scan(
+		&swap.ID,
+		&swap.Kind,
+		&swap.Status,
+		&swap.ShiftID,
+		&swap.RequesterID,
+		&swap.TargetShiftID,
+		&swap.TargetUserID,
+		&swap.AccepterID,
+		&swap.Note,
+		&swap.CreatedAt,
+		&swap.UpdatedAt,
+	)
+}
+
+// ------------------------------
+// Insert
+// ------------------------------
+
+// Insert opens a new swap after checking that the requester works the offered
+// shift and, for exchanges, that the target user works the target shift, all
+// within the organization.
+func (m *SwapModel) Insert(orgID uuid.UUID, swap *Swap) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := m.requireAssignment(ctx, tx, orgID, swap.ShiftID, swap.RequesterID); err != nil {
+		return err
+	}
+	if swap.Kind == SwapExchange {
+		if err := m.requireAssignment(ctx, tx, orgID, *swap.TargetShiftID, *swap.TargetUserID); err != nil {
+			return err
+		}
+	}
+
+	query := `
+		INSERT INTO shift_swaps (kind, shift_id, requester_id, target_shift_id, target_user_id, note)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, status, created_at, updated_at
+	`
+	args := []any{swap.Kind, swap.ShiftID, swap.RequesterID, swap.TargetShiftID, swap.TargetUserID, swap.Note}
+	if err := tx.QueryRowContext(ctx, query, args...).Scan(&swap.ID, &swap.Status, &swap.CreatedAt, &swap.UpdatedAt); err != nil {
+		return err
+	}
+
+	event, err := m.insertEvent(ctx, tx, swap.ID, SwapOpen, swap.RequesterID)
+	if err != nil {
+		return err
+	}
+	swap.Events = []SwapEvent{*event}
+
+	return tx.Commit()
+}
+
+// ------------------------------
+// Select
+// ------------------------------
+func (m *SwapModel) GetByID(orgID, id uuid.UUID) (*Swap, error) {
+	query := `SELECT ` + swapColumns + ` FROM shift_swaps WHERE ` + swapInOrg + ` AND id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	var swap Swap
+	if err := scanSwap(m.DB.QueryRowContext(ctx, query, orgID, id), &swap); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	rows, err := m.DB.QueryContext(ctx, `SELECT status, actor_id, created_at FROM shift_swap_events WHERE swap_id = $1 ORDER BY created_at, id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	swap.Events = []SwapEvent{}
+	for rows.Next() {
+		var event SwapEvent
+		if err := rows.Scan(&event.Status, &event.ActorID, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		swap.Events = append(swap.Events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &swap, nil
+}
+
+// GetForUser returns the swaps the user requested, is targeted by or accepted.
+func (m *SwapModel) GetForUser(userID uuid.UUID) ([]Swap, error) {
+	query := `
+		SELECT ` + swapColumns + `
+		FROM shift_swaps
+		WHERE requester_id = $1 OR target_user_id = $1 OR accepter_id = $1
+		ORDER BY created_at DESC
+	`
+
+	return m.querySwaps(query, userID)
+}
+
+// GetOpenFor returns the open swaps of the organization the user could accept.
+func (m *SwapModel) GetOpenFor(orgID, userID uuid.UUID) ([]Swap, error) {
+	query := `
+		SELECT ` + swapColumns + `
+		FROM shift_swaps
+		WHERE ` + swapInOrg + ` AND status = 'open' AND requester_id <> $2 AND (target_user_id IS NULL OR target_user_id = $2)
+		ORDER BY created_at DESC
+	`
+
+	return m.querySwaps(query, orgID, userID)
+}
+
+func (m *SwapModel) GetByStatus(orgID uuid.UUID, status SwapStatus) ([]Swap, error) {
+	query := `
+		SELECT ` + swapColumns + `
+		FROM shift_swaps
+		WHERE ` + swapInOrg + ` AND status = $2
+		ORDER BY created_at
+	`
+
+	return m.querySwaps(query, orgID, status)
+}
+
+func (m *SwapModel) querySwaps(query string, args ...any) ([]Swap, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	swaps := []Swap{}
+
+	for rows.Next() {
+		var swap Swap
+		if err := scanSwap(rows, &swap); err != nil {
+			return nil, err
+		}
+		swaps = append(swaps, swap)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return swaps, nil
+}
+
+// ------------------------------
+// Transitions
+// ------------------------------
+
+// Cancel withdraws an open swap. Only the requester may cancel.
+func (m *SwapModel) Cancel(orgID, id, requesterID uuid.UUID) error {
+	return m.transition(orgID, id, requesterID, func(ctx context.Context, tx *sql.Tx, swap *Swap) (SwapStatus, error) {
+		if swap.RequesterID != requesterID {
+			return "", ErrSwapNotAllowed
+		}
+		if swap.Status != SwapOpen {
+			return "", ErrSwapInvalidStatus
+		}
+		return SwapCancelled, nil
+	})
+}
+
+// Accept records the accepter on an open swap. The resulting assignments are
+// always checked; with autoApprove they are also applied and the swap is
+// approved straight away, otherwise it waits for an admin.
+func (m *SwapModel) Accept(orgID, id, accepterID uuid.UUID, autoApprove bool) (SwapStatus, error) {
+	var status SwapStatus
+
+	err := m.transition(orgID, id, accepterID, func(ctx context.Context, tx *sql.Tx, swap *Swap) (SwapStatus, error) {
+		if swap.RequesterID == accepterID || (swap.TargetUserID != nil && *swap.TargetUserID != accepterID) {
+			return "", ErrSwapNotAllowed
+		}
+		if swap.Status != SwapOpen {
+			return "", ErrSwapInvalidStatus
+		}
+
+		if _, err := tx.ExecContext(ctx, `UPDATE shift_swaps SET accepter_id = $1 WHERE id = $2`, accepterID, swap.ID); err != nil {
+			return "", err
+		}
+		swap.AccepterID = &accepterID
+
+		if autoApprove {
+			if err := m.apply(ctx, tx, orgID, swap); err != nil {
+				return "", err
+			}
+			status = SwapApproved
+			return status, nil
+		}
+
+		// Dry run: apply inside a savepoint and roll it back
+		if _, err := tx.ExecContext(ctx, `SAVEPOINT swap_check`); err != nil {
+			return "", err
+		}
+		if err := m.apply(ctx, tx, orgID, swap); err != nil {
+			return "", err
+		}
+		if _, err := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT swap_check`); err != nil {
+			return "", err
+		}
+
+		status = SwapAccepted
+		return status, nil
+	})
+
+	return status, err
+}
+
+// Approve applies an accepted swap.
+func (m *SwapModel) Approve(orgID, id, adminID uuid.UUID) error {
+	return m.transition(orgID, id, adminID, func(ctx context.Context, tx *sql.Tx, swap *Swap) (SwapStatus, error) {
+		if swap.Status != SwapAccepted {
+			return "", ErrSwapInvalidStatus
+		}
+		if err := m.apply(ctx, tx, orgID, swap); err != nil {
+			return "", err
+		}
+		return SwapApproved, nil
+	})
+}
+
+// Reject closes an open or accepted swap without changing any assignment.
+func (m *SwapModel) Reject(orgID, id, adminID uuid.UUID) error {
+	return m.transition(orgID, id, adminID, func(ctx context.Context, tx *sql.Tx, swap *Swap) (SwapStatus, error) {
+		if swap.Status != SwapOpen && swap.Status != SwapAccepted {
+			return "", ErrSwapInvalidStatus
+		}
+		return SwapRejected, nil
+	})
+}
+
+// transition locks the swap, lets fn decide the next status (and do any work
+// in the same transaction), then stores the status and its event.
+func (m *SwapModel) transition(orgID, id, actorID uuid.UUID, fn func(ctx context.Context, tx *sql.Tx, swap *Swap) (SwapStatus, error)) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var swap Swap
+	query := `SELECT ` + swapColumns + ` FROM shift_swaps WHERE ` + swapInOrg + ` AND id = $2 FOR UPDATE`
+	if err := scanSwap(tx.QueryRowContext(ctx, query, orgID, id), &swap); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	status, err := fn(ctx, tx, &swap)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE shift_swaps SET status = $1, updated_at = NOW() WHERE id = $2`, status, swap.ID); err != nil {
+		return err
+	}
+
+	if _, err := m.insertEvent(ctx, tx, swap.ID, status, actorID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// apply moves the assignments. The old assignments are removed first so the
+// double-booking check does not trip over the shifts being traded away; the
+// new ones then go through the usual staffing checks.
+func (m *SwapModel) apply(ctx context.Context, tx *sql.Tx, orgID uuid.UUID, swap *Swap) error {
+	shifts := ShiftModel{DB: m.DB, config: m.config}
+
+	if err := m.removeAssignment(ctx, tx, swap.ShiftID, swap.RequesterID); err != nil {
+		return err
+	}
+
+	switch swap.Kind {
+	case SwapGiveaway:
+		if _, err := shifts.assign(ctx, tx, orgID, swap.ShiftID, *swap.AccepterID); err != nil {
+			return err
+		}
+	case SwapExchange:
+		if err := m.removeAssignment(ctx, tx, *swap.TargetShiftID, *swap.TargetUserID); err != nil {
+			return err
+		}
+		if _, err := shifts.assign(ctx, tx, orgID, swap.ShiftID, *swap.TargetUserID); err != nil {
+			return err
+		}
+		if _, err := shifts.assign(ctx, tx, orgID, *swap.TargetShiftID, swap.RequesterID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *SwapModel) requireAssignment(ctx context.Context, tx *sql.Tx, orgID, shiftID, userID uuid.UUID) error {
+	var exists bool
+	query := `
+		SELECT EXISTS(
+			SELECT 1
+			FROM shift_assignments sa
+			JOIN shifts s ON s.id = sa.shift_id
+			WHERE sa.shift_id = $1 AND sa.user_id = $2 AND s.org_id = $3
+		)
+	`
+	if err := tx.QueryRowContext(ctx, query, shiftID, userID, orgID).Scan(&exists); err != nil {
+		return err
+	}
+
+	if !exists {
+		return ErrNotAssigned
+	}
+
+	return nil
+}
+
+func (m *SwapModel) removeAssignment(ctx context.Context, tx *sql.Tx, shiftID, userID uuid.UUID) error {
+	result, err := tx.ExecContext(ctx, `DELETE FROM shift_assignments WHERE shift_id = $1 AND user_id = $2`, shiftID, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotAssigned
+	}
+
+	return nil
+}
+
+func (m *SwapModel) insertEvent(ctx context.Context, tx *sql.Tx, swapID uuid.UUID, status SwapStatus, actorID uuid.UUID) (*SwapEvent, error) {
+	event := SwapEvent{Status: status, ActorID: &actorID}
+
+	query := `
+		INSERT INTO shift_swap_events (swap_id, status, actor_id)
+		VALUES ($1, $2, $3)
+		RETURNING created_at
+	`
+	if err := tx.QueryRowContext(ctx, query, swapID, status, actorID).Scan(&event.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	return &event, nil
+}
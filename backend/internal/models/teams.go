@@ -0,0 +1,354 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jonathanhu237/when-works/backend/internal/config"
+)
+
+var (
+	ErrTeamNameConflict = errors.New("team name already exists")
+)
+
+type Team struct {
+	ID          uuid.UUID `json:"id"`
+	OrgID       uuid.UUID `json:"-"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Membership is a team as seen by one of its members.
+type Membership struct {
+	Team
+	IsManager bool `json:"is_manager"`
+}
+
+type TeamMember struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Username  string    `json:"username"`
+	Name      string    `json:"name"`
+	IsManager bool      `json:"is_manager"`
+	JoinedAt  time.Time `json:"joined_at"`
+}
+
+type TeamModel struct {
+	DB     *sql.DB
+	config config.Config
+}
+
+// scanUUIDArray scans a UUID[] column selected as TEXT[].
+func scanUUIDArray(dest *[]uuid.UUID) sql.Scanner {
+	return &uuidArrayScanner{dest: dest}
+}
+
+type uuidArrayScanner struct {
+	dest *[]uuid.UUID
+}
+
+func (s *uuidArrayScanner) Scan(src any) error {
+	var values []string
+	if err := scanTextArray(&values).Scan(src); err != nil {
+		return err
+	}
+
+	ids := make([]uuid.UUID, len(values))
+	for i, value := range values {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return err
+		}
+		ids[i] = id
+	}
+	*s.dest = ids
+
+	return nil
+}
+
+func teamWriteError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation && pgErr.ConstraintName == "teams_org_id_name_key" {
+		return ErrTeamNameConflict
+	}
+	return err
+}
+
+// ------------------------------
+// Insert
+// ------------------------------
+func (m *TeamModel) Insert(team *Team) error {
+	query := `
+		INSERT INTO teams (org_id, name, description)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	if err := m.DB.QueryRowContext(ctx, query, team.OrgID, team.Name, team.Description).Scan(&team.ID, &team.CreatedAt); err != nil {
+		return teamWriteError(err)
+	}
+
+	return nil
+}
+
+// ------------------------------
+// Select
+// ------------------------------
+func (m *TeamModel) GetAll(orgID uuid.UUID) ([]Team, error) {
+	query := `
+		SELECT id, org_id, name, description, created_at
+		FROM teams
+		WHERE org_id = $1
+		ORDER BY name
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	teams := []Team{}
+	for rows.Next() {
+		var team Team
+		if err := rows.Scan(&team.ID, &team.OrgID, &team.Name, &team.Description, &team.CreatedAt); err != nil {
+			return nil, err
+		}
+		teams = append(teams, team)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return teams, nil
+}
+
+// GetForUser returns the teams the user belongs to.
+func (m *TeamModel) GetForUser(userID uuid.UUID) ([]Membership, error) {
+	query := `
+		SELECT t.id, t.org_id, t.name, t.description, t.created_at, tm.is_manager
+		FROM team_members tm
+		JOIN teams t ON t.id = tm.team_id
+		WHERE tm.user_id = $1
+		ORDER BY t.name
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	memberships := []Membership{}
+	for rows.Next() {
+		var membership Membership
+		if err := rows.Scan(
+			&membership.ID,
+			&membership.OrgID,
+			&membership.Name,
+			&membership.Description,
+			&membership.CreatedAt,
+			&membership.IsManager,
+		); err != nil {
+			return nil, err
+		}
+		memberships = append(memberships, membership)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return memberships, nil
+}
+
+func (m *TeamModel) GetByID(orgID, id uuid.UUID) (*Team, error) {
+	query := `
+		SELECT id, org_id, name, description, created_at
+		FROM teams
+		WHERE id = $1 AND org_id = $2
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	var team Team
+	if err := m.DB.QueryRowContext(ctx, query, id, orgID).Scan(&team.ID, &team.OrgID, &team.Name, &team.Description, &team.CreatedAt); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &team, nil
+}
+
+// GetMembers returns the members of the team, managers first.
+func (m *TeamModel) GetMembers(teamID uuid.UUID) ([]TeamMember, error) {
+	query := `
+		SELECT u.id, u.username, u.name, tm.is_manager, tm.created_at
+		FROM team_members tm
+		JOIN users u ON u.id = tm.user_id
+		WHERE tm.team_id = $1
+		ORDER BY tm.is_manager DESC, u.name
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, teamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []TeamMember{}
+	for rows.Next() {
+		var member TeamMember
+		if err := rows.Scan(&member.UserID, &member.Username, &member.Name, &member.IsManager, &member.JoinedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return members, nil
+}
+
+// ------------------------------
+// Update
+// ------------------------------
+func (m *TeamModel) Update(team *Team) error {
+	query := `
+		UPDATE teams
+		SET name = $1, description = $2
+		WHERE id = $3 AND org_id = $4
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, team.Name, team.Description, team.ID, team.OrgID)
+	if err != nil {
+		return teamWriteError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// SetMember adds the user to the team, or changes whether they manage it if
+// they already belong to it. The team and user must both belong to the
+// organization.
+func (m *TeamModel) SetMember(orgID, teamID, userID uuid.UUID, isManager bool) error {
+	query := `
+		INSERT INTO team_members (team_id, user_id, is_manager)
+		SELECT t.id, u.id, $3
+		FROM teams t, users u
+		WHERE t.id = $1 AND u.id = $2 AND t.org_id = $4 AND u.org_id = $4
+		ON CONFLICT (team_id, user_id) DO UPDATE SET is_manager = EXCLUDED.is_manager
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, teamID, userID, isManager, orgID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// ------------------------------
+// Delete
+// ------------------------------
+func (m *TeamModel) Delete(orgID, id uuid.UUID) error {
+	query := `
+		DELETE FROM teams
+		WHERE id = $1 AND org_id = $2
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, orgID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (m *TeamModel) RemoveMember(orgID, teamID, userID uuid.UUID) error {
+	query := `
+		DELETE FROM team_members tm
+		USING teams t
+		WHERE tm.team_id = $1 AND tm.user_id = $2 AND t.id = tm.team_id AND t.org_id = $3
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, teamID, userID, orgID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
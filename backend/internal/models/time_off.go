@@ -0,0 +1,304 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jonathanhu237/when-works/backend/internal/config"
+)
+
+var (
+	ErrTimeOffInvalidStatus = errors.New("time off request is not pending")
+)
+
+type TimeOffStatus string
+
+const (
+	TimeOffPending   TimeOffStatus = "pending"
+	TimeOffApproved  TimeOffStatus = "approved"
+	TimeOffDenied    TimeOffStatus = "denied"
+	TimeOffCancelled TimeOffStatus = "cancelled"
+)
+
+// TimeOffRequest covers whole days from StartDate to EndDate inclusive,
+// formatted as "YYYY-MM-DD" and read in the user's time zone.
+type TimeOffRequest struct {
+	ID           uuid.UUID     `json:"id"`
+	UserID       uuid.UUID     `json:"user_id"`
+	StartDate    string        `json:"start_date"`
+	EndDate      string        `json:"end_date"`
+	Reason       string        `json:"reason"`
+	Status       TimeOffStatus `json:"status"`
+	DecidedBy    *uuid.UUID    `json:"decided_by"`
+	DecidedAt    *time.Time    `json:"decided_at"`
+	DecisionNote string        `json:"decision_note"`
+	ExceptionID  *uuid.UUID    `json:"exception_id"`
+	CreatedAt    time.Time     `json:"created_at"`
+	UpdatedAt    time.Time     `json:"updated_at"`
+}
+
+type TimeOffModel struct {
+	DB     *sql.DB
+	config config.Config
+}
+
+const timeOffColumns = `
+	id, user_id, to_char(start_date, 'YYYY-MM-DD'), to_char(end_date, 'YYYY-MM-DD'), reason, status,
+	decided_by, decided_at, decision_note, exception_id, created_at, updated_at
+`
+
+// A time-off request belongs to the organization of its user.
+const timeOffInOrg = `user_id IN (SELECT id FROM users WHERE org_id = $1)`
+
+func scanTimeOff(row interface{ Scan(...any) error }, request *TimeOffRequest) error {
+	return row.Scan(
+		&request.ID,
+		&request.UserID,
+		&request.StartDate,
+		&request.EndDate,
+		&request.Reason,
+		&request.Status,
+		&request.DecidedBy,
+		&request.DecidedAt,
+		&request.DecisionNote,
+		&request.ExceptionID,
+		&request.CreatedAt,
+		&request.UpdatedAt,
+	)
+}
+
+// TimeOffRange returns the UTC interval covered by the days from startDate to
+// endDate inclusive in loc.
+func TimeOffRange(startDate, endDate string, loc *time.Location) (time.Time, time.Time, error) {
+	start, err := time.ParseInLocation(time.DateOnly, startDate, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	end, err := time.ParseInLocation(time.DateOnly, endDate, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	return start.UTC(), end.AddDate(0, 0, 1).UTC(), nil
+}
+
+// ------------------------------
+// Insert
+// ------------------------------
+func (m *TimeOffModel) Insert(request *TimeOffRequest) error {
+	query := `
+		INSERT INTO time_off_requests (user_id, start_date, end_date, reason)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, status, created_at, updated_at
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	args := []any{request.UserID, request.StartDate, request.EndDate, request.Reason}
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&request.ID, &request.Status, &request.CreatedAt, &request.UpdatedAt)
+}
+
+// ------------------------------
+// Select
+// ------------------------------
+func (m *TimeOffModel) GetByID(orgID, id uuid.UUID) (*TimeOffRequest, error) {
+	query := `SELECT ` + timeOffColumns + ` FROM time_off_requests WHERE ` + timeOffInOrg + ` AND id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	var request TimeOffRequest
+	if err := scanTimeOff(m.DB.QueryRowContext(ctx, query, orgID, id), &request); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &request, nil
+}
+
+func (m *TimeOffModel) GetForUser(userID uuid.UUID) ([]TimeOffRequest, error) {
+	query := `
+		SELECT ` + timeOffColumns + `
+		FROM time_off_requests
+		WHERE user_id = $1
+		ORDER BY start_date DESC, created_at DESC
+	`
+
+	return m.queryTimeOff(query, userID)
+}
+
+// GetAll returns every request in the organization, or only those with the
+// given status when it is not empty. Pending requests are the admin's queue,
+// so oldest come first.
+func (m *TimeOffModel) GetAll(orgID uuid.UUID, status TimeOffStatus) ([]TimeOffRequest, error) {
+	query := `
+		SELECT ` + timeOffColumns + `
+		FROM time_off_requests
+		WHERE ` + timeOffInOrg + ` AND ($2 = '' OR status::text = $2)
+		ORDER BY created_at
+	`
+
+	return m.queryTimeOff(query, orgID, string(status))
+}
+
+func (m *TimeOffModel) queryTimeOff(query string, args ...any) ([]TimeOffRequest, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests := []TimeOffRequest{}
+
+	for rows.Next() {
+		var request TimeOffRequest
+		if err := scanTimeOff(rows, &request); err != nil {
+			return nil, err
+		}
+		requests = append(requests, request)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return requests, nil
+}
+
+// ------------------------------
+// Decisions
+// ------------------------------
+
+// Cancel withdraws a pending request of the user.
+func (m *TimeOffModel) Cancel(orgID, id, userID uuid.UUID) error {
+	return m.decide(orgID, id, userID, func(ctx context.Context, tx *sql.Tx, request *TimeOffRequest) error {
+		query := `
+			UPDATE time_off_requests
+			SET status = 'cancelled', updated_at = NOW()
+			WHERE id = $1
+		`
+		_, err := tx.ExecContext(ctx, query, id)
+		return err
+	})
+}
+
+// Approve accepts a pending request and blocks the days with an
+// "unavailable" availability exception in the user's time zone. It returns
+// the exception so callers can look for shifts it conflicts with.
+func (m *TimeOffModel) Approve(orgID, id, adminID uuid.UUID, note string) (*AvailabilityException, error) {
+	var exception *AvailabilityException
+
+	err := m.decide(orgID, id, uuid.Nil, func(ctx context.Context, tx *sql.Tx, request *TimeOffRequest) error {
+		var timeZone string
+		if err := tx.QueryRowContext(ctx, `SELECT time_zone FROM users WHERE id = $1`, request.UserID).Scan(&timeZone); err != nil {
+			return err
+		}
+
+		loc, err := time.LoadLocation(timeZone)
+		if err != nil {
+			return err
+		}
+
+		start, end, err := TimeOffRange(request.StartDate, request.EndDate, loc)
+		if err != nil {
+			return err
+		}
+
+		exception = &AvailabilityException{
+			UserID:  request.UserID,
+			Kind:    ExceptionUnavailable,
+			StartAt: start,
+			EndAt:   end,
+			Note:    "Time off",
+		}
+		if request.Reason != "" {
+			exception.Note += ": " + request.Reason
+		}
+
+		query := `
+			INSERT INTO availability_exceptions (user_id, kind, start_at, end_at, note)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id, created_at
+		`
+		args := []any{exception.UserID, exception.Kind, exception.StartAt, exception.EndAt, exception.Note}
+		if err := tx.QueryRowContext(ctx, query, args...).Scan(&exception.ID, &exception.CreatedAt); err != nil {
+			return err
+		}
+
+		query = `
+			UPDATE time_off_requests
+			SET status = 'approved', decided_by = $1, decided_at = NOW(), decision_note = $2, exception_id = $3, updated_at = NOW()
+			WHERE id = $4
+		`
+		_, err = tx.ExecContext(ctx, query, adminID, note, exception.ID, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return exception, nil
+}
+
+func (m *TimeOffModel) Deny(orgID, id, adminID uuid.UUID, note string) error {
+	return m.decide(orgID, id, uuid.Nil, func(ctx context.Context, tx *sql.Tx, request *TimeOffRequest) error {
+		query := `
+			UPDATE time_off_requests
+			SET status = 'denied', decided_by = $1, decided_at = NOW(), decision_note = $2, updated_at = NOW()
+			WHERE id = $3
+		`
+		_, err := tx.ExecContext(ctx, query, adminID, note, id)
+		return err
+	})
+}
+
+// decide locks a pending request of the organization and runs fn in the same
+// transaction. When ownerID is not uuid.Nil, requests of other users are
+// reported as not found.
+func (m *TimeOffModel) decide(orgID, id, ownerID uuid.UUID, fn func(ctx context.Context, tx *sql.Tx, request *TimeOffRequest) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var request TimeOffRequest
+	query := `SELECT ` + timeOffColumns + ` FROM time_off_requests WHERE ` + timeOffInOrg + ` AND id = $2 FOR UPDATE`
+	if err := scanTimeOff(tx.QueryRowContext(ctx, query, orgID, id), &request); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	if ownerID != uuid.Nil && request.UserID != ownerID {
+		return ErrRecordNotFound
+	}
+
+	if request.Status != TimeOffPending {
+		return ErrTimeOffInvalidStatus
+	}
+
+	if err := fn(ctx, tx, &request); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,130 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jonathanhu237/when-works/backend/internal/config"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid or expired token")
+)
+
+// Scopes of the single-use tokens kept in the tokens table.
+const (
+	ScopePasswordReset = "password-reset"
+	ScopeActivation    = "activation"
+	ScopeTOTPChallenge = "totp-challenge"
+)
+
+// generateToken returns a random URL-safe token and the SHA-256 hash that is
+// stored in its place. The plaintext is only ever handed to its recipient.
+func generateToken() (string, []byte, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, err
+	}
+
+	plaintext := base64.RawURLEncoding.EncodeToString(b)
+	return plaintext, hashToken(plaintext), nil
+}
+
+func hashToken(plaintext string) []byte {
+	hash := sha256.Sum256([]byte(plaintext))
+	return hash[:]
+}
+
+type TokenModel struct {
+	DB     *sql.DB
+	config config.Config
+}
+
+// New issues a token for the user in the given scope and returns its
+// plaintext. Earlier tokens of the same scope stop working, so only the most
+// recent email link is valid.
+func (m *TokenModel) New(userID uuid.UUID, ttl time.Duration, scope string) (string, error) {
+	token, tokenHash, err := generateToken()
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM tokens WHERE user_id = $1 AND scope = $2`, userID, scope); err != nil {
+		return "", err
+	}
+
+	query := `
+		INSERT INTO tokens (hash, user_id, scope, expiry)
+		VALUES ($1, $2, $3, $4)
+	`
+	if _, err := tx.ExecContext(ctx, query, tokenHash, userID, scope, time.Now().Add(ttl)); err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// Consume deletes the token and returns the user it was issued to. Deleting
+// and reading in one statement makes the token single-use even under
+// concurrent requests.
+func (m *TokenModel) Consume(scope, token string) (uuid.UUID, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	return consumeToken(ctx, m.DB, scope, token)
+}
+
+// consumeToken is Consume on an open transaction, so the token is only used
+// up if whatever it authorizes commits with it.
+func consumeToken(ctx context.Context, q rowQueryer, scope, token string) (uuid.UUID, error) {
+	query := `
+		DELETE FROM tokens
+		WHERE hash = $1 AND scope = $2 AND expiry > NOW()
+		RETURNING user_id
+	`
+
+	var userID uuid.UUID
+	if err := q.QueryRowContext(ctx, query, hashToken(token), scope).Scan(&userID); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return uuid.Nil, ErrInvalidToken
+		default:
+			return uuid.Nil, err
+		}
+	}
+
+	return userID, nil
+}
+
+// DeleteAllForUser removes the user's tokens in the given scope.
+func (m *TokenModel) DeleteAllForUser(scope string, userID uuid.UUID) error {
+	query := `
+		DELETE FROM tokens
+		WHERE scope = $1 AND user_id = $2
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, scope, userID)
+	return err
+}
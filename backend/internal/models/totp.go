@@ -0,0 +1,329 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jonathanhu237/when-works/backend/internal/config"
+)
+
+var (
+	ErrTOTPAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTOTPCodeReused     = errors.New("authentication code has already been used")
+)
+
+const recoveryCodeCount = 10
+
+// TOTP is a user's authenticator app enrollment. Until it is confirmed with
+// a code the app produced, it has no effect on signing in.
+type TOTP struct {
+	UserID      uuid.UUID  `json:"-"`
+	Secret      string     `json:"-"`
+	ConfirmedAt *time.Time `json:"confirmed_at"`
+	LastCounter *int64     `json:"-"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func (t *TOTP) Enabled() bool {
+	return t.ConfirmedAt != nil
+}
+
+type TOTPModel struct {
+	DB     *sql.DB
+	config config.Config
+}
+
+// generateRecoveryCodes returns fresh recovery codes formatted for display,
+// e.g. "k7m2q-x9p4t", along with the hashes to store.
+func generateRecoveryCodes() ([]string, [][]byte, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([][]byte, recoveryCodeCount)
+
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	for i := range codes {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+
+		raw := strings.ToLower(encoding.EncodeToString(b))[:10]
+		codes[i] = raw[:5] + "-" + raw[5:]
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode ignores case, dashes and spaces so a code is accepted the
+// way people tend to type it.
+func hashRecoveryCode(code string) []byte {
+	code = strings.ToLower(code)
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+	return hashToken(code)
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID uuid.UUID, hashes [][]byte) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	for _, hash := range hashes {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, hash); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ------------------------------
+// Insert
+// ------------------------------
+
+// Begin stores a new, unconfirmed secret for the user, replacing any earlier
+// unconfirmed one. It returns ErrTOTPAlreadyEnabled if the user has already
+// confirmed an enrollment.
+func (m *TOTPModel) Begin(userID uuid.UUID, secret string) error {
+	query := `
+		INSERT INTO user_totp (user_id, secret)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, created_at = NOW()
+		WHERE user_totp.confirmed_at IS NULL
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, userID, secret)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrTOTPAlreadyEnabled
+	}
+
+	return nil
+}
+
+// ------------------------------
+// Select
+// ------------------------------
+func (m *TOTPModel) Get(userID uuid.UUID) (*TOTP, error) {
+	query := `
+		SELECT user_id, secret, confirmed_at, last_counter, created_at
+		FROM user_totp
+		WHERE user_id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	var t TOTP
+	if err := m.DB.QueryRowContext(ctx, query, userID).Scan(
+		&t.UserID,
+		&t.Secret,
+		&t.ConfirmedAt,
+		&t.LastCounter,
+		&t.CreatedAt,
+	); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &t, nil
+}
+
+// RecoveryCodesLeft counts the user's unused recovery codes.
+func (m *TOTPModel) RecoveryCodesLeft(userID uuid.UUID) (int, error) {
+	query := `SELECT COUNT(*) FROM recovery_codes WHERE user_id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	var count int
+	if err := m.DB.QueryRowContext(ctx, query, userID).Scan(&count); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// ------------------------------
+// Update
+// ------------------------------
+
+// Confirm turns on two-factor authentication once the user has proven their
+// app produces matching codes, with counter being the step of that code. It
+// returns the recovery codes, which are only ever shown this once.
+func (m *TOTPModel) Confirm(userID uuid.UUID, counter int64) ([]string, error) {
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE user_totp
+		SET confirmed_at = NOW(), last_counter = $2
+		WHERE user_id = $1 AND confirmed_at IS NULL
+	`
+	result, err := tx.ExecContext(ctx, query, userID, counter)
+	if err != nil {
+		return nil, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if rowsAffected == 0 {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, hashes); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// UseCounter records that the code of the given step was used to sign in. It
+// returns ErrTOTPCodeReused if that step, or a later one, was already used.
+func (m *TOTPModel) UseCounter(userID uuid.UUID, counter int64) error {
+	query := `
+		UPDATE user_totp
+		SET last_counter = $2
+		WHERE user_id = $1 AND confirmed_at IS NOT NULL
+		AND (last_counter IS NULL OR last_counter < $2)
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, userID, counter)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrTOTPCodeReused
+	}
+
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces all of the user's recovery codes.
+func (m *TOTPModel) RegenerateRecoveryCodes(userID uuid.UUID) ([]string, error) {
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, hashes); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// ------------------------------
+// Delete
+// ------------------------------
+
+// UseRecoveryCode spends one of the user's recovery codes. It returns
+// ErrRecordNotFound if the code does not match an unused one.
+func (m *TOTPModel) UseRecoveryCode(userID uuid.UUID, code string) error {
+	query := `
+		DELETE FROM recovery_codes
+		WHERE user_id = $1 AND code_hash = $2
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, userID, hashRecoveryCode(code))
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// Disable removes the enrollment and the recovery codes.
+func (m *TOTPModel) Disable(userID uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_totp WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
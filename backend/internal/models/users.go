@@ -3,7 +3,12 @@ package models
 import (
 	"context"
 	"database/sql"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -17,14 +22,74 @@ var (
 	ErrEmailConflict    = errors.New("email already exists")
 )
 
+var (
+	ErrUserInvalidStatus = errors.New("the user is not in a state that allows this action")
+	ErrUserPurgeTooEarly = errors.New("the grace period of the archived user has not passed")
+)
+
+// Deactivated users cannot sign in until restored. Archived users are left
+// out of every query unless asked for, as if deleted, but keep their history
+// until purged.
+const (
+	UserStatusInvited     = "invited"
+	UserStatusActive      = "active"
+	UserStatusDeactivated = "deactivated"
+	UserStatusArchived    = "archived"
+)
+
 type User struct {
-	ID           uuid.UUID `json:"id"`
-	Username     string    `json:"username"`
-	Email        string    `json:"email"`
-	Name         string    `json:"name"`
-	PasswordHash string    `json:"-"`
-	IsAdmin      bool      `json:"is_admin"`
-	CreatedAt    string    `json:"created_at"`
+	ID           uuid.UUID  `json:"id"`
+	OrgID        uuid.UUID  `json:"org_id"`
+	Username     string     `json:"username"`
+	Email        string     `json:"email"`
+	Name         string     `json:"name"`
+	PasswordHash string     `json:"-"`
+	Roles        []string   `json:"roles"`
+	Status       string     `json:"status"`
+	TimeZone     string     `json:"time_zone"`
+	CreatedAt    string     `json:"created_at"`
+	ArchivedAt   *time.Time `json:"archived_at"`
+}
+
+// UserScope limits user queries to an organization, and within it possibly to
+// the members of some teams. Build it with AllUsers or TeamMembers; the zero
+// value matches nobody.
+type UserScope struct {
+	orgID    uuid.UUID
+	all      bool
+	teamIDs  []uuid.UUID
+	archived bool
+}
+
+func AllUsers(orgID uuid.UUID) UserScope {
+	return UserScope{orgID: orgID, all: true}
+}
+
+func TeamMembers(orgID uuid.UUID, teamIDs []uuid.UUID) UserScope {
+	return UserScope{orgID: orgID, teamIDs: teamIDs}
+}
+
+// WithArchived returns the scope extended to archived users.
+func (s UserScope) WithArchived() UserScope {
+	s.archived = true
+	return s
+}
+
+// scopeCondition restricts a query on users to a UserScope whose args come
+// first.
+const scopeCondition = `
+	org_id = $1
+	AND ($2 OR id IN (SELECT user_id FROM team_members WHERE team_id = ANY($3::uuid[])))
+	AND ($4 OR status <> 'archived')
+`
+
+// args returns the query arguments of scopeCondition.
+func (s UserScope) args() []any {
+	ids := make([]string, len(s.teamIDs))
+	for i, id := range s.teamIDs {
+		ids[i] = id.String()
+	}
+	return []any{s.orgID, s.all, ids, s.archived}
 }
 
 type UserModel struct {
@@ -32,8 +97,21 @@ type UserModel struct {
 	config config.Config
 }
 
-func (m *UserModel) AdminExists() (bool, error) {
-	query := `SELECT EXISTS(SELECT 1 FROM users WHERE is_admin = TRUE)`
+// rowQueryer is satisfied by both *sql.DB and *sql.Tx.
+type rowQueryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// SuperadminExists reports whether any user holds the superadmin role.
+func (m *UserModel) SuperadminExists() (bool, error) {
+	query := `
+		SELECT EXISTS(
+			SELECT 1
+			FROM user_roles ur
+			JOIN roles r ON r.id = ur.role_id
+			WHERE r.name = 'superadmin' AND r.built_in
+		)
+	`
 
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
 	defer cancel()
@@ -50,17 +128,23 @@ func (m *UserModel) AdminExists() (bool, error) {
 // Insert
 // ------------------------------
 func (m *UserModel) Insert(user *User) error {
-	query := `
-		INSERT INTO users (username, email, name, password_hash, is_admin)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, created_at
-	`
-
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
 	defer cancel()
 
-	args := []any{user.Username, user.Email, user.Name, user.PasswordHash, user.IsAdmin}
-	if err := m.DB.QueryRowContext(ctx, query, args...).Scan(&user.ID, &user.CreatedAt); err != nil {
+	return insertUser(ctx, m.DB, user)
+}
+
+// insertUser inserts the user with q, so that organizations can be created
+// together with their first admin.
+func insertUser(ctx context.Context, q rowQueryer, user *User) error {
+	query := `
+		INSERT INTO users (org_id, username, email, name, password_hash, status)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, time_zone, created_at
+	`
+
+	args := []any{user.OrgID, user.Username, user.Email, user.Name, user.PasswordHash, user.Status}
+	if err := q.QueryRowContext(ctx, query, args...).Scan(&user.ID, &user.TimeZone, &user.CreatedAt); err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
 			switch pgErr.Code {
@@ -75,6 +159,7 @@ func (m *UserModel) Insert(user *User) error {
 		}
 		return err
 	}
+	user.Roles = []string{}
 
 	return nil
 }
@@ -84,9 +169,14 @@ func (m *UserModel) Insert(user *User) error {
 // ------------------------------
 func (m *UserModel) GetByUsername(username string) (*User, error) {
 	query := `
-		SELECT id, username, email, name, password_hash, is_admin, created_at
+		SELECT id, org_id, username, email, name, password_hash,
+			ARRAY(
+				SELECT r.name FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+				WHERE ur.user_id = users.id ORDER BY r.name
+			),
+			status, time_zone, created_at, archived_at
 		FROM users
-		WHERE username = $1
+		WHERE username = $1 AND status <> 'archived'
 	`
 
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
@@ -95,12 +185,16 @@ func (m *UserModel) GetByUsername(username string) (*User, error) {
 	var user User
 	if err := m.DB.QueryRowContext(ctx, query, username).Scan(
 		&user.ID,
+		&user.OrgID,
 		&user.Username,
 		&user.Email,
 		&user.Name,
 		&user.PasswordHash,
-		&user.IsAdmin,
+		scanTextArray(&user.Roles),
+		&user.Status,
+		&user.TimeZone,
 		&user.CreatedAt,
+		&user.ArchivedAt,
 	); err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -113,11 +207,18 @@ func (m *UserModel) GetByUsername(username string) (*User, error) {
 	return &user, nil
 }
 
+// GetByID does not check the organization. It serves the requester's own
+// account and sign-in flows; routes about other users check InScope first.
 func (m *UserModel) GetByID(id uuid.UUID) (*User, error) {
 	query := `
-		SELECT id, username, email, name, password_hash, is_admin, created_at
+		SELECT id, org_id, username, email, name, password_hash,
+			ARRAY(
+				SELECT r.name FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+				WHERE ur.user_id = users.id ORDER BY r.name
+			),
+			status, time_zone, created_at, archived_at
 		FROM users
-		WHERE id = $1
+		WHERE id = $1 AND status <> 'archived'
 	`
 
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
@@ -126,12 +227,16 @@ func (m *UserModel) GetByID(id uuid.UUID) (*User, error) {
 	var user User
 	if err := m.DB.QueryRowContext(ctx, query, id).Scan(
 		&user.ID,
+		&user.OrgID,
 		&user.Username,
 		&user.Email,
 		&user.Name,
 		&user.PasswordHash,
-		&user.IsAdmin,
+		scanTextArray(&user.Roles),
+		&user.Status,
+		&user.TimeZone,
 		&user.CreatedAt,
+		&user.ArchivedAt,
 	); err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -144,17 +249,107 @@ func (m *UserModel) GetByID(id uuid.UUID) (*User, error) {
 	return &user, nil
 }
 
-func (m *UserModel) GetAll() ([]User, error) {
+// GetByEmail looks the user up by email address, ignoring case.
+func (m *UserModel) GetByEmail(email string) (*User, error) {
 	query := `
-		SELECT id, username, email, name, password_hash, is_admin, created_at
+		SELECT id, org_id, username, email, name, password_hash,
+			ARRAY(
+				SELECT r.name FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+				WHERE ur.user_id = users.id ORDER BY r.name
+			),
+			status, time_zone, created_at, archived_at
 		FROM users
+		WHERE lower(email) = lower($1) AND status <> 'archived'
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	var user User
+	if err := m.DB.QueryRowContext(ctx, query, email).Scan(
+		&user.ID,
+		&user.OrgID,
+		&user.Username,
+		&user.Email,
+		&user.Name,
+		&user.PasswordHash,
+		scanTextArray(&user.Roles),
+		&user.Status,
+		&user.TimeZone,
+		&user.CreatedAt,
+		&user.ArchivedAt,
+	); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &user, nil
+}
+
+// GetByCalendarToken returns the user a calendar subscription token belongs to.
+// Subscriptions of users who are not active stop resolving.
+func (m *UserModel) GetByCalendarToken(token string) (*User, error) {
+	query := `
+		SELECT id, org_id, username, email, name, password_hash,
+			ARRAY(
+				SELECT r.name FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+				WHERE ur.user_id = users.id ORDER BY r.name
+			),
+			status, time_zone, created_at, archived_at
+		FROM users
+		WHERE calendar_token_hash = $1 AND status = 'active'
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	var user User
+	if err := m.DB.QueryRowContext(ctx, query, hashToken(token)).Scan(
+		&user.ID,
+		&user.OrgID,
+		&user.Username,
+		&user.Email,
+		&user.Name,
+		&user.PasswordHash,
+		scanTextArray(&user.Roles),
+		&user.Status,
+		&user.TimeZone,
+		&user.CreatedAt,
+		&user.ArchivedAt,
+	); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &user, nil
+}
+
+// GetAll returns the users within the scope, newest first.
+func (m *UserModel) GetAll(scope UserScope) ([]User, error) {
+	query := `
+		SELECT id, org_id, username, email, name, password_hash,
+			ARRAY(
+				SELECT r.name FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+				WHERE ur.user_id = users.id ORDER BY r.name
+			),
+			status, time_zone, created_at, archived_at
+		FROM users
+		WHERE ` + scopeCondition + `
 		ORDER BY created_at DESC
 	`
 
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
 	defer cancel()
 
-	rows, err := m.DB.QueryContext(ctx, query)
+	rows, err := m.DB.QueryContext(ctx, query, scope.args()...)
 	if err != nil {
 		return nil, err
 	}
@@ -167,12 +362,16 @@ func (m *UserModel) GetAll() ([]User, error) {
 
 		if err := rows.Scan(
 			&user.ID,
+			&user.OrgID,
 			&user.Username,
 			&user.Email,
 			&user.Name,
 			&user.PasswordHash,
-			&user.IsAdmin,
+			scanTextArray(&user.Roles),
+			&user.Status,
+			&user.TimeZone,
 			&user.CreatedAt,
+			&user.ArchivedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -187,22 +386,303 @@ func (m *UserModel) GetAll() ([]User, error) {
 	return users, nil
 }
 
+// UserFilter narrows and orders a page of the user list. Zero fields do not
+// filter.
+type UserFilter struct {
+	// Search matches a substring of the username, name or email, ignoring case.
+	Search string
+	// Status also reaches archived users, who are left out otherwise.
+	Status        string
+	Role          string
+	TeamID        *uuid.UUID
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	// Sort is one of UserSortKeys, prefixed with "-" to sort descending.
+	Sort  string
+	Limit int
+	// After continues the list past the last user of a previous page.
+	After *UserCursor
+}
+
+// UserSortKeys are the columns the user list can be sorted by.
+var UserSortKeys = []string{"created_at", "username", "name", "email"}
+
+// UserCursor is the position of a user in the list under one sort order.
+// Clients only see it encoded.
+type UserCursor struct {
+	Sort  string    `json:"s"`
+	Value string    `json:"v"`
+	ID    uuid.UUID `json:"id"`
+}
+
+func (c *UserCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeUserCursor parses a cursor returned with a previous page.
+func DecodeUserCursor(s string) (*UserCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var cursor UserCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, err
+	}
+	if !slices.Contains(UserSortKeys, strings.TrimPrefix(cursor.Sort, "-")) {
+		return nil, errors.New("unknown sort key")
+	}
+
+	return &cursor, nil
+}
+
+// List returns a page of the users within the scope that match the filter,
+// and the cursor of the next page if there is one. Pages are keyed on the sort
+// column and the ID, so they stay stable while users are added.
+func (m *UserModel) List(scope UserScope, filter UserFilter) ([]User, *UserCursor, error) {
+	column, descending := strings.TrimPrefix(filter.Sort, "-"), strings.HasPrefix(filter.Sort, "-")
+	if !slices.Contains(UserSortKeys, column) {
+		return nil, nil, fmt.Errorf("unknown sort key %q", filter.Sort)
+	}
+
+	if filter.Status == UserStatusArchived {
+		scope = scope.WithArchived()
+	}
+
+	args := scope.args()
+	arg := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	conditions := []string{scopeCondition}
+	if filter.Search != "" {
+		pattern := arg("%" + likeEscaper.Replace(filter.Search) + "%")
+		conditions = append(conditions, fmt.Sprintf("(username ILIKE %[1]s OR name ILIKE %[1]s OR email ILIKE %[1]s)", pattern))
+	}
+	if filter.Status != "" {
+		conditions = append(conditions, "status = "+arg(filter.Status))
+	}
+	if filter.Role != "" {
+		conditions = append(conditions, `id IN (
+			SELECT ur.user_id FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+			WHERE r.name = `+arg(filter.Role)+` AND (r.org_id IS NULL OR r.org_id = $1)
+		)`)
+	}
+	if filter.TeamID != nil {
+		conditions = append(conditions, "id IN (SELECT user_id FROM team_members WHERE team_id = "+arg(*filter.TeamID)+")")
+	}
+	if filter.CreatedAfter != nil {
+		conditions = append(conditions, "created_at >= "+arg(*filter.CreatedAfter))
+	}
+	if filter.CreatedBefore != nil {
+		conditions = append(conditions, "created_at < "+arg(*filter.CreatedBefore))
+	}
+
+	// The cursor value travels as text, so it is cast back to the column type
+	cast, direction, comparison := "", "ASC", ">"
+	if column == "created_at" {
+		cast = "::text::timestamptz"
+	}
+	if descending {
+		direction, comparison = "DESC", "<"
+	}
+	if filter.After != nil {
+		conditions = append(conditions, fmt.Sprintf("(%s, id) %s (%s%s, %s)", column, comparison, arg(filter.After.Value), cast, arg(filter.After.ID)))
+	}
+
+	query := `
+		SELECT id, org_id, username, email, name, password_hash,
+			ARRAY(
+				SELECT r.name FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+				WHERE ur.user_id = users.id ORDER BY r.name
+			),
+			status, time_zone, created_at, archived_at
+		FROM users
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY ` + column + ` ` + direction + `, id ` + direction + `
+		LIMIT ` + arg(filter.Limit+1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	users := []User{}
+
+	for rows.Next() {
+		var user User
+
+		if err := rows.Scan(
+			&user.ID,
+			&user.OrgID,
+			&user.Username,
+			&user.Email,
+			&user.Name,
+			&user.PasswordHash,
+			scanTextArray(&user.Roles),
+			&user.Status,
+			&user.TimeZone,
+			&user.CreatedAt,
+			&user.ArchivedAt,
+		); err != nil {
+			return nil, nil, err
+		}
+
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	// One row more than the limit was asked for to tell if another page follows
+	if len(users) <= filter.Limit {
+		return users, nil, nil
+	}
+	users = users[:filter.Limit]
+
+	last := users[len(users)-1]
+	next := &UserCursor{Sort: filter.Sort, ID: last.ID}
+	switch column {
+	case "created_at":
+		next.Value = last.CreatedAt
+	case "username":
+		next.Value = last.Username
+	case "name":
+		next.Value = last.Name
+	case "email":
+		next.Value = last.Email
+	}
+
+	return users, next, nil
+}
+
+// likeEscaper escapes the wildcards of LIKE patterns.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// GetManyByID returns the users of the organization matching the given IDs.
+// IDs that do not exist there are silently skipped, so callers should compare
+// lengths when that matters.
+func (m *UserModel) GetManyByID(orgID uuid.UUID, ids []uuid.UUID) ([]User, error) {
+	return m.GetManyInScope(AllUsers(orgID), ids)
+}
+
+// GetManyInScope returns the users within the scope matching the given IDs,
+// skipping the others like GetManyByID.
+func (m *UserModel) GetManyInScope(scope UserScope, ids []uuid.UUID) ([]User, error) {
+	query := `
+		SELECT id, org_id, username, email, name, password_hash,
+			ARRAY(
+				SELECT r.name FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+				WHERE ur.user_id = users.id ORDER BY r.name
+			),
+			status, time_zone, created_at, archived_at
+		FROM users
+		WHERE id = ANY($5::uuid[]) AND ` + scopeCondition + `
+		ORDER BY username
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	idStrings := make([]string, len(ids))
+	for i, id := range ids {
+		idStrings[i] = id.String()
+	}
+
+	rows, err := m.DB.QueryContext(ctx, query, append(scope.args(), idStrings)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []User
+
+	for rows.Next() {
+		var user User
+
+		if err := rows.Scan(
+			&user.ID,
+			&user.OrgID,
+			&user.Username,
+			&user.Email,
+			&user.Name,
+			&user.PasswordHash,
+			scanTextArray(&user.Roles),
+			&user.Status,
+			&user.TimeZone,
+			&user.CreatedAt,
+			&user.ArchivedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// CountInScope returns how many of the given users exist within the scope.
+func (m *UserModel) CountInScope(ids []uuid.UUID, scope UserScope) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM users
+		WHERE id = ANY($5::uuid[]) AND ` + scopeCondition
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	idStrings := make([]string, len(ids))
+	for i, id := range ids {
+		idStrings[i] = id.String()
+	}
+
+	var count int
+	if err := m.DB.QueryRowContext(ctx, query, append(scope.args(), idStrings)...).Scan(&count); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// InScope reports whether the user exists within the scope.
+func (m *UserModel) InScope(id uuid.UUID, scope UserScope) (bool, error) {
+	count, err := m.CountInScope([]uuid.UUID{id}, scope)
+	if err != nil {
+		return false, err
+	}
+
+	return count == 1, nil
+}
+
 // ------------------------------
 // Update
 // ------------------------------
 func (m *UserModel) Update(user *User) error {
 	query := `
 		UPDATE users
-		SET name = $1, email = $2, is_admin = $3, password_hash = $4
-		WHERE id = $5
-		RETURNING username, is_admin, created_at
+		SET name = $1, email = $2, password_hash = $3, time_zone = $4
+		WHERE id = $5 AND org_id = $6
+		RETURNING username, created_at
 	`
 
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
 	defer cancel()
 
-	args := []any{user.Name, user.Email, user.IsAdmin, user.PasswordHash, user.ID}
-	if err := m.DB.QueryRowContext(ctx, query, args...).Scan(&user.Username, &user.IsAdmin, &user.CreatedAt); err != nil {
+	args := []any{user.Name, user.Email, user.PasswordHash, user.TimeZone, user.ID, user.OrgID}
+	if err := m.DB.QueryRowContext(ctx, query, args...).Scan(&user.Username, &user.CreatedAt); err != nil {
 		var pgErr *pgconn.PgError
 
 		switch {
@@ -228,13 +708,261 @@ func (m *UserModel) Update(user *User) error {
 	return nil
 }
 
+// Activate consumes an activation token, sets the first password of the
+// invited user it was issued to and makes the account usable. Both happen in
+// one transaction, so a failed update leaves the token valid. It returns
+// ErrRecordNotFound if the user is no longer in the invited state.
+func (m *UserModel) Activate(token, passwordHash string) error {
+	query := `
+		UPDATE users
+		SET password_hash = $1, status = 'active'
+		WHERE id = $2 AND status = 'invited'
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	id, err := consumeToken(ctx, tx, ScopeActivation, token)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, query, passwordHash, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return tx.Commit()
+}
+
+// ResetPassword consumes a password reset token and sets the password of the
+// user it was issued to in one transaction, returning that user's ID. It
+// returns ErrInvalidToken if the token is unknown or expired, and
+// ErrRecordNotFound if the user has since been archived or purged.
+func (m *UserModel) ResetPassword(token, passwordHash string) (uuid.UUID, error) {
+	query := `
+		UPDATE users
+		SET password_hash = $1
+		WHERE id = $2 AND status <> 'archived'
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer tx.Rollback()
+
+	id, err := consumeToken(ctx, tx, ScopePasswordReset, token)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	result, err := tx.ExecContext(ctx, query, passwordHash, id)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if rowsAffected == 0 {
+		return uuid.Nil, ErrRecordNotFound
+	}
+
+	if err := tx.Commit(); err != nil {
+		return uuid.Nil, err
+	}
+
+	return id, nil
+}
+
+// RotateCalendarToken replaces the user's calendar subscription token and
+// returns the new plaintext. The previous subscription URL stops working.
+func (m *UserModel) RotateCalendarToken(id uuid.UUID) (string, error) {
+	token, tokenHash, err := generateToken()
+	if err != nil {
+		return "", err
+	}
+
+	if err := m.setCalendarTokenHash(id, tokenHash); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// RevokeCalendarToken disables the user's calendar subscription.
+func (m *UserModel) RevokeCalendarToken(id uuid.UUID) error {
+	return m.setCalendarTokenHash(id, nil)
+}
+
+func (m *UserModel) setCalendarTokenHash(id uuid.UUID, tokenHash []byte) error {
+	query := `
+		UPDATE users
+		SET calendar_token_hash = $1
+		WHERE id = $2
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, tokenHash, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// Deactivate stops an active user from signing in while keeping them in the
+// lists.
+func (m *UserModel) Deactivate(orgID, id uuid.UUID) error {
+	return m.transition(orgID, id, []string{UserStatusActive}, `status = 'deactivated'`)
+}
+
+// Archive hides the user as if deleted but keeps their history. It is what
+// deleting a user does; Purge removes the row once the grace period is over.
+func (m *UserModel) Archive(orgID, id uuid.UUID) error {
+	return m.transition(
+		orgID, id,
+		[]string{UserStatusInvited, UserStatusActive, UserStatusDeactivated},
+		`status = 'archived', archived_at = NOW()`,
+	)
+}
+
+// Restore brings back a deactivated or archived user. Users who never set a
+// password go back to the invited state.
+func (m *UserModel) Restore(orgID, id uuid.UUID) error {
+	return m.transition(
+		orgID, id,
+		[]string{UserStatusDeactivated, UserStatusArchived},
+		`status = CASE WHEN password_hash = '' THEN 'invited'::user_status ELSE 'active' END, archived_at = NULL`,
+	)
+}
+
+// transition applies set to the user if their status is one of from, and
+// returns ErrUserInvalidStatus otherwise. It refuses with ErrLastSuperadmin to
+// leave the organization without a superadmin who can still sign in.
+func (m *UserModel) transition(orgID, id uuid.UUID, from []string, set string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status string
+	query := `SELECT status FROM users WHERE id = $1 AND org_id = $2 FOR UPDATE`
+	if err := tx.QueryRowContext(ctx, query, id, orgID).Scan(&status); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	if !slices.Contains(from, status) {
+		return ErrUserInvalidStatus
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE users SET `+set+` WHERE id = $1`, id); err != nil {
+		return err
+	}
+
+	left, err := superadminLeft(ctx, tx, orgID)
+	if err != nil {
+		return err
+	}
+	if !left {
+		return ErrLastSuperadmin
+	}
+
+	return tx.Commit()
+}
+
 // ------------------------------
 // Delete
 // ------------------------------
-func (m *UserModel) Delete(id uuid.UUID) error {
+
+// Purge permanently deletes an archived user, and with them everything that
+// belongs to them, once gracePeriod has passed since they were archived. Until
+// then it returns ErrUserPurgeTooEarly and the time the user can be purged.
+func (m *UserModel) Purge(orgID, id uuid.UUID, gracePeriod time.Duration) (time.Time, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer tx.Rollback()
+
+	var status string
+	var archivedAt *time.Time
+	query := `SELECT status, archived_at FROM users WHERE id = $1 AND org_id = $2 FOR UPDATE`
+	if err := tx.QueryRowContext(ctx, query, id, orgID).Scan(&status, &archivedAt); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return time.Time{}, ErrRecordNotFound
+		default:
+			return time.Time{}, err
+		}
+	}
+
+	if status != UserStatusArchived || archivedAt == nil {
+		return time.Time{}, ErrUserInvalidStatus
+	}
+
+	purgeableAt := archivedAt.Add(gracePeriod)
+	if time.Now().Before(purgeableAt) {
+		return purgeableAt, ErrUserPurgeTooEarly
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, id); err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Time{}, tx.Commit()
+}
+
+// DeleteInvited removes a user that never accepted the invitation. It returns
+// ErrRecordNotFound if there is no such user still in the invited state.
+func (m *UserModel) DeleteInvited(id uuid.UUID) error {
 	query := `
 		DELETE FROM users
-		WHERE id = $1
+		WHERE id = $1 AND status = 'invited'
 	`
 
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(m.config.Database.QueryTimeout)*time.Second)
@@ -0,0 +1,65 @@
+// Package ratelimit implements token bucket rate limiting. A bucket holds up
+// to Limit.Requests tokens and refills at Limit.Requests per Limit.Period;
+// every request takes one token, so clients may burst up to the full limit
+// and then continue at the refill rate.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// perSecond is the refill rate of the bucket.
+func (l Limit) perSecond() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+// Result describes the bucket after a request has tried to take a token.
+type Result struct {
+	Allowed bool
+	Limit   int
+	// Remaining is the number of whole tokens left.
+	Remaining int
+	// RetryAfter is how long until the next token, zero if one is left.
+	RetryAfter time.Duration
+	// ResetAfter is how long until the bucket is full again.
+	ResetAfter time.Duration
+}
+
+// Store keeps the buckets. Taking a token has to be atomic so concurrent
+// requests, possibly on different instances, cannot overdraw a bucket.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// newResult describes a bucket left with the given tokens.
+func newResult(limit Limit, allowed bool, tokens float64) Result {
+	rate := limit.perSecond()
+
+	result := Result{
+		Allowed:    allowed,
+		Limit:      limit.Requests,
+		Remaining:  int(math.Floor(tokens)),
+		ResetAfter: time.Duration((float64(limit.Requests) - tokens) / rate * float64(time.Second)),
+	}
+	if tokens < 1 {
+		result.RetryAfter = time.Duration((1 - tokens) / rate * float64(time.Second))
+	}
+
+	return result
+}
+
+// refill returns the tokens of a bucket that held tokens elapsed ago.
+func refill(limit Limit, tokens float64, elapsed time.Duration) float64 {
+	if elapsed < 0 {
+		elapsed = 0
+	}
+
+	return math.Min(float64(limit.Requests), tokens+elapsed.Seconds()*limit.perSecond())
+}
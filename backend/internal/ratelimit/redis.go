@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// takeScript refills and takes from a bucket stored as a hash of its tokens
+// and the time they were counted, using the Redis clock so instances with
+// skewed clocks agree. Tokens are returned as a string because Lua numbers
+// are truncated to integers on the way out.
+var takeScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local per_ms = tonumber(ARGV[2])
+
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+	tokens = capacity
+	ts = now
+end
+
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * per_ms)
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil((capacity - tokens) / per_ms) + 1000)
+
+return {allowed, tostring(tokens)}
+`)
+
+// RedisStore keeps buckets in Redis so the limit holds across instances.
+type RedisStore struct {
+	client *redis.Client
+}
+
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+func (s *RedisStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	perMillisecond := limit.perSecond() / 1000
+
+	values, err := takeScript.Run(ctx, s.client, []string{"ratelimit:" + key}, limit.Requests, perMillisecond).Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	if len(values) != 2 {
+		return Result{}, fmt.Errorf("unexpected rate limit script result: %v", values)
+	}
+
+	allowed, _ := values[0].(int64)
+	tokensText, _ := values[1].(string)
+	tokens, err := strconv.ParseFloat(tokensText, 64)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return newResult(limit, allowed == 1, tokens), nil
+}
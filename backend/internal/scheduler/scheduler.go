@@ -0,0 +1,219 @@
+// Package scheduler proposes shift assignments from availability. It has no
+// database access: callers load shifts, availability and existing bookings,
+// and Generate returns the same result for the same input.
+package scheduler
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jonathanhu237/when-works/backend/internal/models"
+)
+
+// Reasons a candidate could not take a shift.
+const (
+	ReasonAlreadyAssigned  = "already_assigned"
+	ReasonUnavailable      = "unavailable"
+	ReasonDoubleBooked     = "double_booked"
+	ReasonInsufficientRest = "insufficient_rest"
+	ReasonMaxHoursExceeded = "max_hours_exceeded"
+)
+
+type Shift struct {
+	ID                uuid.UUID
+	StartAt           time.Time
+	EndAt             time.Time
+	RequiredHeadcount int
+	AssignedUserIDs   []uuid.UUID
+}
+
+type Candidate struct {
+	UserID uuid.UUID
+	// Location is used to bucket hours into weeks. Nil means UTC.
+	Location *time.Location
+	// Availability is the candidate's resolved availability.
+	Availability []models.Interval
+	// Bookings are shifts the candidate already works, including ones outside
+	// the shifts being scheduled, so rest and weekly hours account for them.
+	Bookings []models.Interval
+}
+
+type Limits struct {
+	// MaxHoursPerWeek caps the hours per ISO week. Zero means no limit.
+	MaxHoursPerWeek float64
+	// MinRest is the minimum gap between two shifts of the same person.
+	MinRest time.Duration
+}
+
+type Assignment struct {
+	ShiftID uuid.UUID `json:"shift_id"`
+	UserID  uuid.UUID `json:"user_id"`
+}
+
+type UnfilledShift struct {
+	ShiftID  uuid.UUID `json:"shift_id"`
+	Required int       `json:"required"`
+	Assigned int       `json:"assigned"`
+	Missing  int       `json:"missing"`
+	// Reasons counts, per reason, how many candidates were turned down for
+	// the last open seat. An empty map means there were no candidates at all.
+	Reasons map[string]int `json:"reasons"`
+}
+
+type Result struct {
+	Assignments []Assignment    `json:"assignments"`
+	Unfilled    []UnfilledShift `json:"unfilled"`
+}
+
+type weekKey struct {
+	year, week int
+}
+
+type candidateState struct {
+	Candidate
+	bookings []models.Interval
+	hours    map[weekKey]float64
+	total    time.Duration
+}
+
+// Generate fills the shifts greedily in start-time order. For each open seat
+// it picks, among the candidates that pass every rule, the one with the fewest
+// hours booked so far, breaking ties by user ID.
+func Generate(shifts []Shift, candidates []Candidate, limits Limits) Result {
+	result := Result{Assignments: []Assignment{}, Unfilled: []UnfilledShift{}}
+
+	states := make([]*candidateState, 0, len(candidates))
+	for _, candidate := range candidates {
+		if candidate.Location == nil {
+			candidate.Location = time.UTC
+		}
+
+		state := &candidateState{
+			Candidate: candidate,
+			bookings:  slices.Clone(candidate.Bookings),
+			hours:     make(map[weekKey]float64),
+		}
+		for _, booking := range candidate.Bookings {
+			state.hours[state.week(booking.Start)] += booking.Duration().Hours()
+			state.total += booking.Duration()
+		}
+
+		states = append(states, state)
+	}
+
+	slices.SortFunc(states, func(a, b *candidateState) int {
+		return cmp.Compare(a.UserID.String(), b.UserID.String())
+	})
+
+	ordered := slices.Clone(shifts)
+	slices.SortFunc(ordered, func(a, b Shift) int {
+		if c := a.StartAt.Compare(b.StartAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID.String(), b.ID.String())
+	})
+
+	for _, shift := range ordered {
+		assigned := make(map[uuid.UUID]bool, len(shift.AssignedUserIDs))
+		for _, userID := range shift.AssignedUserIDs {
+			assigned[userID] = true
+		}
+
+		reasons := make(map[string]int)
+		missing := shift.RequiredHeadcount - len(assigned)
+
+		for missing > 0 {
+			var best *candidateState
+			clear(reasons)
+
+			for _, state := range states {
+				if assigned[state.UserID] {
+					reasons[ReasonAlreadyAssigned]++
+					continue
+				}
+				if reason := state.reject(shift, limits); reason != "" {
+					reasons[reason]++
+					continue
+				}
+				if best == nil || state.total < best.total {
+					best = state
+				}
+			}
+
+			if best == nil {
+				break
+			}
+
+			best.book(shift)
+			assigned[best.UserID] = true
+			result.Assignments = append(result.Assignments, Assignment{ShiftID: shift.ID, UserID: best.UserID})
+			missing--
+		}
+
+		if missing > 0 {
+			result.Unfilled = append(result.Unfilled, UnfilledShift{
+				ShiftID:  shift.ID,
+				Required: shift.RequiredHeadcount,
+				Assigned: shift.RequiredHeadcount - missing,
+				Missing:  missing,
+				Reasons:  maps.Clone(reasons),
+			})
+		}
+	}
+
+	return result
+}
+
+// reject returns why the candidate cannot take the shift, or "" if they can.
+func (s *candidateState) reject(shift Shift, limits Limits) string {
+	if !covers(s.Availability, shift.StartAt, shift.EndAt) {
+		return ReasonUnavailable
+	}
+
+	for _, booking := range s.bookings {
+		if booking.Overlaps(shift.StartAt, shift.EndAt) {
+			return ReasonDoubleBooked
+		}
+	}
+
+	if limits.MinRest > 0 {
+		for _, booking := range s.bookings {
+			if booking.Overlaps(shift.StartAt.Add(-limits.MinRest), shift.EndAt.Add(limits.MinRest)) {
+				return ReasonInsufficientRest
+			}
+		}
+	}
+
+	if limits.MaxHoursPerWeek > 0 {
+		hours := shift.EndAt.Sub(shift.StartAt).Hours()
+		if s.hours[s.week(shift.StartAt)]+hours > limits.MaxHoursPerWeek {
+			return ReasonMaxHoursExceeded
+		}
+	}
+
+	return ""
+}
+
+func (s *candidateState) book(shift Shift) {
+	booking := models.Interval{Start: shift.StartAt, End: shift.EndAt}
+	s.bookings = append(s.bookings, booking)
+	s.hours[s.week(shift.StartAt)] += booking.Duration().Hours()
+	s.total += booking.Duration()
+}
+
+func (s *candidateState) week(t time.Time) weekKey {
+	year, week := t.In(s.Location).ISOWeek()
+	return weekKey{year: year, week: week}
+}
+
+func covers(intervals []models.Interval, start, end time.Time) bool {
+	for _, interval := range intervals {
+		if interval.Covers(start, end) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,230 @@
+package scheduler
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jonathanhu237/when-works/backend/internal/models"
+)
+
+var (
+	userA = uuid.MustParse("00000000-0000-0000-0000-00000000000a")
+	userB = uuid.MustParse("00000000-0000-0000-0000-00000000000b")
+	userC = uuid.MustParse("00000000-0000-0000-0000-00000000000c")
+
+	shift1 = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	shift2 = uuid.MustParse("00000000-0000-0000-0000-000000000002")
+)
+
+// monday is the start of an ISO week, so all test shifts share one.
+var monday = time.Date(2025, time.March, 3, 0, 0, 0, 0, time.UTC)
+
+func at(hour int) time.Time {
+	return monday.Add(time.Duration(hour) * time.Hour)
+}
+
+func newShift(id uuid.UUID, start, end, required int, assigned ...uuid.UUID) Shift {
+	return Shift{ID: id, StartAt: at(start), EndAt: at(end), RequiredHeadcount: required, AssignedUserIDs: assigned}
+}
+
+// allWeek is available for the whole test week.
+func allWeek(userID uuid.UUID) Candidate {
+	return Candidate{
+		UserID:       userID,
+		Availability: []models.Interval{{Start: monday, End: monday.AddDate(0, 0, 7)}},
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		name       string
+		shifts     []Shift
+		candidates []Candidate
+		limits     Limits
+		want       Result
+	}{
+		{
+			name:       "ties go to the lowest user ID",
+			shifts:     []Shift{newShift(shift1, 9, 17, 1)},
+			candidates: []Candidate{allWeek(userC), allWeek(userB), allWeek(userA)},
+			want: Result{
+				Assignments: []Assignment{{ShiftID: shift1, UserID: userA}},
+				Unfilled:    []UnfilledShift{},
+			},
+		},
+		{
+			name:   "fewest booked hours win over user ID",
+			shifts: []Shift{newShift(shift1, 9, 17, 1)},
+			candidates: []Candidate{
+				func() Candidate {
+					c := allWeek(userA)
+					c.Bookings = []models.Interval{{Start: at(30), End: at(34)}}
+					return c
+				}(),
+				allWeek(userB),
+			},
+			want: Result{
+				Assignments: []Assignment{{ShiftID: shift1, UserID: userB}},
+				Unfilled:    []UnfilledShift{},
+			},
+		},
+		{
+			name:       "shifts are filled in start-time order and hours balance out",
+			shifts:     []Shift{newShift(shift2, 33, 41, 1), newShift(shift1, 9, 17, 1)},
+			candidates: []Candidate{allWeek(userA), allWeek(userB)},
+			want: Result{
+				Assignments: []Assignment{
+					{ShiftID: shift1, UserID: userA},
+					{ShiftID: shift2, UserID: userB},
+				},
+				Unfilled: []UnfilledShift{},
+			},
+		},
+		{
+			name:       "only the open seats are filled",
+			shifts:     []Shift{newShift(shift1, 9, 17, 2, userC)},
+			candidates: []Candidate{allWeek(userA), allWeek(userB), allWeek(userC)},
+			want: Result{
+				Assignments: []Assignment{{ShiftID: shift1, UserID: userA}},
+				Unfilled:    []UnfilledShift{},
+			},
+		},
+		{
+			name:       "missing head-count is reported",
+			shifts:     []Shift{newShift(shift1, 9, 17, 3)},
+			candidates: []Candidate{allWeek(userA), allWeek(userB)},
+			want: Result{
+				Assignments: []Assignment{
+					{ShiftID: shift1, UserID: userA},
+					{ShiftID: shift1, UserID: userB},
+				},
+				Unfilled: []UnfilledShift{{
+					ShiftID:  shift1,
+					Required: 3,
+					Assigned: 2,
+					Missing:  1,
+					Reasons:  map[string]int{ReasonAlreadyAssigned: 2},
+				}},
+			},
+		},
+		{
+			name:   "candidates unavailable for the whole shift are skipped",
+			shifts: []Shift{newShift(shift1, 9, 17, 1)},
+			candidates: []Candidate{
+				{UserID: userA, Availability: []models.Interval{{Start: at(9), End: at(12)}}},
+				allWeek(userB),
+			},
+			want: Result{
+				Assignments: []Assignment{{ShiftID: shift1, UserID: userB}},
+				Unfilled:    []UnfilledShift{},
+			},
+		},
+		{
+			name:   "availability split in two does not cover the shift",
+			shifts: []Shift{newShift(shift1, 9, 17, 1)},
+			candidates: []Candidate{{
+				UserID:       userA,
+				Availability: []models.Interval{{Start: at(9), End: at(12)}, {Start: at(13), End: at(17)}},
+			}},
+			want: Result{
+				Assignments: []Assignment{},
+				Unfilled: []UnfilledShift{{
+					ShiftID:  shift1,
+					Required: 1,
+					Assigned: 0,
+					Missing:  1,
+					Reasons:  map[string]int{ReasonUnavailable: 1},
+				}},
+			},
+		},
+		{
+			name:   "bookings exclude overlapping shifts",
+			shifts: []Shift{newShift(shift1, 9, 17, 1)},
+			candidates: []Candidate{func() Candidate {
+				c := allWeek(userA)
+				c.Bookings = []models.Interval{{Start: at(16), End: at(20)}}
+				return c
+			}()},
+			want: Result{
+				Assignments: []Assignment{},
+				Unfilled: []UnfilledShift{{
+					ShiftID:  shift1,
+					Required: 1,
+					Assigned: 0,
+					Missing:  1,
+					Reasons:  map[string]int{ReasonDoubleBooked: 1},
+				}},
+			},
+		},
+		{
+			name:       "no candidates at all",
+			shifts:     []Shift{newShift(shift1, 9, 17, 1)},
+			candidates: nil,
+			want: Result{
+				Assignments: []Assignment{},
+				Unfilled: []UnfilledShift{{
+					ShiftID:  shift1,
+					Required: 1,
+					Assigned: 0,
+					Missing:  1,
+					Reasons:  map[string]int{},
+				}},
+			},
+		},
+		{
+			name:       "minimum rest between shifts",
+			shifts:     []Shift{newShift(shift1, 9, 17, 1), newShift(shift2, 20, 23, 1)},
+			candidates: []Candidate{allWeek(userA)},
+			limits:     Limits{MinRest: 8 * time.Hour},
+			want: Result{
+				Assignments: []Assignment{{ShiftID: shift1, UserID: userA}},
+				Unfilled: []UnfilledShift{{
+					ShiftID:  shift2,
+					Required: 1,
+					Assigned: 0,
+					Missing:  1,
+					Reasons:  map[string]int{ReasonInsufficientRest: 1},
+				}},
+			},
+		},
+		{
+			name:       "weekly hours limit",
+			shifts:     []Shift{newShift(shift1, 9, 17, 1), newShift(shift2, 33, 41, 1)},
+			candidates: []Candidate{allWeek(userA)},
+			limits:     Limits{MaxHoursPerWeek: 12},
+			want: Result{
+				Assignments: []Assignment{{ShiftID: shift1, UserID: userA}},
+				Unfilled: []UnfilledShift{{
+					ShiftID:  shift2,
+					Required: 1,
+					Assigned: 0,
+					Missing:  1,
+					Reasons:  map[string]int{ReasonMaxHoursExceeded: 1},
+				}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Generate(tt.shifts, tt.candidates, tt.limits)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Generate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateIsDeterministic(t *testing.T) {
+	shifts := []Shift{newShift(shift1, 9, 17, 2), newShift(shift2, 33, 41, 2)}
+	candidates := []Candidate{allWeek(userC), allWeek(userA), allWeek(userB)}
+	reversed := []Candidate{allWeek(userB), allWeek(userA), allWeek(userC)}
+
+	first := Generate(shifts, candidates, Limits{})
+	second := Generate(shifts, reversed, Limits{})
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("candidate order changed the result: %+v vs %+v", first, second)
+	}
+}
@@ -1,4 +1,4 @@
-import axios, { AxiosError } from "axios";
+import axios, { AxiosError, type InternalAxiosRequestConfig } from "axios";
 import { isApiError, type ApiError, type User } from "./types";
 import { queryOptions } from "@tanstack/react-query";
 
@@ -10,9 +10,34 @@ const api = axios.create({
   },
 });
 
+// Access tokens are short-lived. On a 401, refresh the session once and
+// replay the request; concurrent failures share the same refresh call.
+let refreshing: Promise<unknown> | null = null;
+
 api.interceptors.response.use(
   (response) => response,
-  (error: AxiosError) => {
+  async (error: AxiosError) => {
+    const request = error.config as
+      | (InternalAxiosRequestConfig & { _retried?: boolean })
+      | undefined;
+    if (
+      error.response?.status === 401 &&
+      request &&
+      !request._retried &&
+      !request.url?.startsWith("/v1/auth/")
+    ) {
+      request._retried = true;
+      refreshing ??= api.post("/v1/auth/refresh").finally(() => {
+        refreshing = null;
+      });
+      try {
+        await refreshing;
+        return api(request);
+      } catch {
+        // Fall through and report the original error
+      }
+    }
+
     if (error.response && isApiError(error.response.data)) {
       return Promise.reject(error.response.data);
     }
@@ -16,9 +16,13 @@ export function isApiError(error: unknown): error is ApiError {
 
 export interface User {
   id: string;
+  org_id: string;
   username: string;
   email: string;
   name: string;
-  is_admin: boolean;
+  roles: string[];
+  status: "invited" | "active" | "deactivated" | "archived";
+  time_zone: string;
   created_at: string;
+  archived_at: string | null;
 }
@@ -0,0 +1,11 @@
+CREATE TABLE availability_windows (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    day_of_week SMALLINT NOT NULL CHECK (day_of_week BETWEEN 0 AND 6),
+    start_time TIME NOT NULL,
+    end_time TIME NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT availability_windows_time_order CHECK (start_time < end_time)
+);
+
+CREATE INDEX availability_windows_user_id_idx ON availability_windows (user_id, day_of_week, start_time);
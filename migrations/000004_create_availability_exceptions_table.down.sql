@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS availability_exceptions;
+DROP TYPE IF EXISTS availability_exception_kind;
@@ -0,0 +1,14 @@
+CREATE TYPE availability_exception_kind AS ENUM ('unavailable', 'available');
+
+CREATE TABLE availability_exceptions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind availability_exception_kind NOT NULL,
+    start_at TIMESTAMPTZ NOT NULL,
+    end_at TIMESTAMPTZ NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT availability_exceptions_time_order CHECK (start_at < end_at)
+);
+
+CREATE INDEX availability_exceptions_user_id_idx ON availability_exceptions (user_id, start_at);
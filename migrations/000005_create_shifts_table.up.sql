@@ -0,0 +1,13 @@
+CREATE TABLE shifts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    title TEXT NOT NULL,
+    start_at TIMESTAMPTZ NOT NULL,
+    end_at TIMESTAMPTZ NOT NULL,
+    required_headcount INTEGER NOT NULL CHECK (required_headcount > 0),
+    tag TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT shifts_time_order CHECK (start_at < end_at)
+);
+
+CREATE INDEX shifts_start_at_idx ON shifts (start_at);
@@ -0,0 +1,9 @@
+CREATE TABLE shift_assignments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    shift_id UUID NOT NULL REFERENCES shifts(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT shift_assignments_shift_id_user_id_key UNIQUE (shift_id, user_id)
+);
+
+CREATE INDEX shift_assignments_user_id_idx ON shift_assignments (user_id);
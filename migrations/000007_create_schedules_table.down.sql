@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS schedule_versions;
+DROP TABLE IF EXISTS schedules;
+DROP TYPE IF EXISTS schedule_status;
@@ -0,0 +1,23 @@
+CREATE TYPE schedule_status AS ENUM ('draft', 'published', 'archived');
+
+CREATE TABLE schedules (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    title TEXT NOT NULL,
+    period_start TIMESTAMPTZ NOT NULL,
+    period_end TIMESTAMPTZ NOT NULL,
+    status schedule_status NOT NULL DEFAULT 'draft',
+    version INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT schedules_period_order CHECK (period_start < period_end)
+);
+
+CREATE TABLE schedule_versions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    schedule_id UUID NOT NULL REFERENCES schedules(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    entries JSONB NOT NULL,
+    published_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    published_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT schedule_versions_schedule_id_version_key UNIQUE (schedule_id, version)
+);
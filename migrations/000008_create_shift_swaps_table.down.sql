@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS shift_swap_events;
+DROP TABLE IF EXISTS shift_swaps;
+DROP TYPE IF EXISTS shift_swap_status;
+DROP TYPE IF EXISTS shift_swap_kind;
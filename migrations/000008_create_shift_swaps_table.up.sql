@@ -0,0 +1,33 @@
+CREATE TYPE shift_swap_kind AS ENUM ('giveaway', 'swap');
+CREATE TYPE shift_swap_status AS ENUM ('open', 'accepted', 'approved', 'rejected', 'cancelled');
+
+CREATE TABLE shift_swaps (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    kind shift_swap_kind NOT NULL,
+    status shift_swap_status NOT NULL DEFAULT 'open',
+    shift_id UUID NOT NULL REFERENCES shifts(id) ON DELETE CASCADE,
+    requester_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    target_shift_id UUID REFERENCES shifts(id) ON DELETE CASCADE,
+    target_user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    accepter_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT shift_swaps_swap_target CHECK (
+        (kind = 'swap' AND target_shift_id IS NOT NULL AND target_user_id IS NOT NULL)
+        OR (kind = 'giveaway' AND target_shift_id IS NULL)
+    )
+);
+
+CREATE INDEX shift_swaps_requester_id_idx ON shift_swaps (requester_id);
+CREATE INDEX shift_swaps_status_idx ON shift_swaps (status);
+
+CREATE TABLE shift_swap_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    swap_id UUID NOT NULL REFERENCES shift_swaps(id) ON DELETE CASCADE,
+    status shift_swap_status NOT NULL,
+    actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX shift_swap_events_swap_id_idx ON shift_swap_events (swap_id, created_at);
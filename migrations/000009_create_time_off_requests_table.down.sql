@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS time_off_requests;
+DROP TYPE IF EXISTS time_off_status;
@@ -0,0 +1,20 @@
+CREATE TYPE time_off_status AS ENUM ('pending', 'approved', 'denied', 'cancelled');
+
+CREATE TABLE time_off_requests (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    status time_off_status NOT NULL DEFAULT 'pending',
+    decided_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    decided_at TIMESTAMPTZ,
+    decision_note TEXT NOT NULL DEFAULT '',
+    exception_id UUID REFERENCES availability_exceptions(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT time_off_requests_date_range CHECK (start_date <= end_date)
+);
+
+CREATE INDEX time_off_requests_user_id_idx ON time_off_requests (user_id, start_date);
+CREATE INDEX time_off_requests_status_idx ON time_off_requests (status);
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS poll_votes;
+DROP TABLE IF EXISTS poll_participants;
+ALTER TABLE IF EXISTS polls DROP CONSTRAINT IF EXISTS polls_confirmed_option_id_fkey;
+DROP TABLE IF EXISTS poll_options;
+DROP TABLE IF EXISTS polls;
+DROP TYPE IF EXISTS poll_vote_choice;
+DROP TYPE IF EXISTS poll_status;
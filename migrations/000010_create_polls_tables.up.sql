@@ -0,0 +1,50 @@
+CREATE TYPE poll_status AS ENUM ('open', 'closed');
+CREATE TYPE poll_vote_choice AS ENUM ('yes', 'maybe', 'no');
+
+CREATE TABLE polls (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    owner_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    title TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    status poll_status NOT NULL DEFAULT 'open',
+    confirmed_option_id UUID,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX polls_owner_id_idx ON polls (owner_id);
+
+CREATE TABLE poll_options (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    poll_id UUID NOT NULL REFERENCES polls(id) ON DELETE CASCADE,
+    start_at TIMESTAMPTZ NOT NULL,
+    end_at TIMESTAMPTZ NOT NULL,
+    CONSTRAINT poll_options_time_range CHECK (start_at < end_at)
+);
+
+CREATE INDEX poll_options_poll_id_idx ON poll_options (poll_id, start_at);
+
+ALTER TABLE polls
+    ADD CONSTRAINT polls_confirmed_option_id_fkey
+    FOREIGN KEY (confirmed_option_id) REFERENCES poll_options(id) ON DELETE SET NULL;
+
+CREATE TABLE poll_participants (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    poll_id UUID NOT NULL REFERENCES polls(id) ON DELETE CASCADE,
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    email TEXT NOT NULL,
+    name TEXT NOT NULL DEFAULT '',
+    token_hash BYTEA NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT poll_participants_poll_id_email_key UNIQUE (poll_id, email)
+);
+
+CREATE INDEX poll_participants_user_id_idx ON poll_participants (user_id);
+
+CREATE TABLE poll_votes (
+    participant_id UUID NOT NULL REFERENCES poll_participants(id) ON DELETE CASCADE,
+    option_id UUID NOT NULL REFERENCES poll_options(id) ON DELETE CASCADE,
+    choice poll_vote_choice NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (participant_id, option_id)
+);
@@ -0,0 +1,11 @@
+CREATE TABLE busy_blocks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    start_at TIMESTAMPTZ NOT NULL,
+    end_at TIMESTAMPTZ NOT NULL,
+    summary TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT busy_blocks_time_range CHECK (start_at < end_at)
+);
+
+CREATE INDEX busy_blocks_user_id_start_at_idx ON busy_blocks (user_id, start_at);
@@ -0,0 +1,3 @@
+CREATE TYPE user_status AS ENUM ('invited', 'active');
+
+ALTER TABLE users ADD COLUMN status user_status NOT NULL DEFAULT 'active';
@@ -0,0 +1,57 @@
+CREATE TABLE roles (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT UNIQUE NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    built_in BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE permissions (
+    name TEXT PRIMARY KEY,
+    description TEXT NOT NULL
+);
+
+CREATE TABLE role_permissions (
+    role_id UUID NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    permission TEXT NOT NULL REFERENCES permissions(name) ON DELETE CASCADE,
+    PRIMARY KEY (role_id, permission)
+);
+
+CREATE TABLE user_roles (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role_id UUID NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    PRIMARY KEY (user_id, role_id)
+);
+
+CREATE INDEX user_roles_role_id_idx ON user_roles (role_id);
+
+INSERT INTO permissions (name, description) VALUES
+    ('users.read', 'View users, their availability and sessions, and the roles'),
+    ('users.manage', 'Create, edit, invite, sign out and delete users'),
+    ('roles.manage', 'Create and edit roles and assign them to users'),
+    ('shifts.read', 'View shifts, assignments and schedules'),
+    ('shifts.manage', 'Edit shifts and assignments, run the scheduler and publish schedules'),
+    ('time_off.read', 'View time-off requests of all users'),
+    ('time_off.manage', 'Approve and deny time-off requests'),
+    ('swaps.read', 'View shift swaps of all users'),
+    ('swaps.manage', 'Approve and reject shift swaps');
+
+INSERT INTO roles (name, description, built_in) VALUES
+    ('superadmin', 'Full access to everything', TRUE),
+    ('scheduler', 'Manages shifts, schedules, time off and swaps', TRUE),
+    ('auditor', 'Read-only access to everything', TRUE);
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT r.id, p.name
+FROM roles r, permissions p
+WHERE r.name = 'superadmin'
+   OR (r.name = 'scheduler' AND p.name IN ('users.read', 'shifts.read', 'shifts.manage', 'time_off.read', 'time_off.manage', 'swaps.read', 'swaps.manage'))
+   OR (r.name = 'auditor' AND p.name LIKE '%.read');
+
+-- Existing admins keep full access
+INSERT INTO user_roles (user_id, role_id)
+SELECT u.id, r.id
+FROM users u, roles r
+WHERE u.is_admin = TRUE AND r.name = 'superadmin';
+
+ALTER TABLE users DROP COLUMN is_admin;
@@ -0,0 +1,5 @@
+DELETE FROM permissions WHERE name = 'teams.manage';
+
+DROP TABLE IF EXISTS team_members;
+
+DROP TABLE IF EXISTS teams;
@@ -0,0 +1,24 @@
+CREATE TABLE teams (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT UNIQUE NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE team_members (
+    team_id UUID NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    is_manager BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (team_id, user_id)
+);
+
+CREATE INDEX team_members_user_id_idx ON team_members (user_id);
+
+INSERT INTO permissions (name, description) VALUES
+    ('teams.manage', 'Create and edit teams and choose their members and managers');
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT id, 'teams.manage'
+FROM roles
+WHERE name = 'superadmin';
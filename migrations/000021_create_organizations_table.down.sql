@@ -0,0 +1,21 @@
+DELETE FROM permissions WHERE name = 'organizations.manage';
+
+ALTER TABLE roles DROP CONSTRAINT roles_org_id_name_key;
+ALTER TABLE roles ADD CONSTRAINT roles_name_key UNIQUE (name);
+ALTER TABLE roles DROP CONSTRAINT roles_org_id_check;
+ALTER TABLE roles DROP COLUMN org_id;
+
+ALTER TABLE teams DROP CONSTRAINT teams_org_id_name_key;
+ALTER TABLE teams ADD CONSTRAINT teams_name_key UNIQUE (name);
+ALTER TABLE teams DROP COLUMN org_id;
+
+ALTER TABLE polls DROP COLUMN org_id;
+
+ALTER TABLE schedules DROP COLUMN org_id;
+
+CREATE INDEX shifts_start_at_idx ON shifts (start_at);
+ALTER TABLE shifts DROP COLUMN org_id;
+
+ALTER TABLE users DROP COLUMN org_id;
+
+DROP TABLE IF EXISTS organizations;
@@ -0,0 +1,60 @@
+CREATE TABLE organizations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT UNIQUE NOT NULL,
+    host BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- The host organization runs the deployment and is the only one that may
+-- create others
+CREATE UNIQUE INDEX organizations_host_key ON organizations (host) WHERE host;
+
+-- Existing data moves to a first organization, which becomes the host
+INSERT INTO organizations (name, host)
+SELECT 'Default', TRUE
+WHERE EXISTS (SELECT 1 FROM users)
+   OR EXISTS (SELECT 1 FROM shifts)
+   OR EXISTS (SELECT 1 FROM schedules)
+   OR EXISTS (SELECT 1 FROM teams)
+   OR EXISTS (SELECT 1 FROM roles WHERE NOT built_in);
+
+ALTER TABLE users ADD COLUMN org_id UUID REFERENCES organizations(id) ON DELETE CASCADE;
+UPDATE users SET org_id = (SELECT id FROM organizations);
+ALTER TABLE users ALTER COLUMN org_id SET NOT NULL;
+CREATE INDEX users_org_id_idx ON users (org_id);
+
+ALTER TABLE shifts ADD COLUMN org_id UUID REFERENCES organizations(id) ON DELETE CASCADE;
+UPDATE shifts SET org_id = (SELECT id FROM organizations);
+ALTER TABLE shifts ALTER COLUMN org_id SET NOT NULL;
+DROP INDEX shifts_start_at_idx;
+CREATE INDEX shifts_org_id_start_at_idx ON shifts (org_id, start_at);
+
+ALTER TABLE schedules ADD COLUMN org_id UUID REFERENCES organizations(id) ON DELETE CASCADE;
+UPDATE schedules SET org_id = (SELECT id FROM organizations);
+ALTER TABLE schedules ALTER COLUMN org_id SET NOT NULL;
+CREATE INDEX schedules_org_id_idx ON schedules (org_id);
+
+ALTER TABLE polls ADD COLUMN org_id UUID REFERENCES organizations(id) ON DELETE CASCADE;
+UPDATE polls SET org_id = (SELECT id FROM organizations);
+ALTER TABLE polls ALTER COLUMN org_id SET NOT NULL;
+
+ALTER TABLE teams ADD COLUMN org_id UUID REFERENCES organizations(id) ON DELETE CASCADE;
+UPDATE teams SET org_id = (SELECT id FROM organizations);
+ALTER TABLE teams ALTER COLUMN org_id SET NOT NULL;
+ALTER TABLE teams DROP CONSTRAINT teams_name_key;
+ALTER TABLE teams ADD CONSTRAINT teams_org_id_name_key UNIQUE (org_id, name);
+
+-- Built-in roles are shared by every organization, custom roles belong to one
+ALTER TABLE roles ADD COLUMN org_id UUID REFERENCES organizations(id) ON DELETE CASCADE;
+UPDATE roles SET org_id = (SELECT id FROM organizations) WHERE NOT built_in;
+ALTER TABLE roles ADD CONSTRAINT roles_org_id_check CHECK (built_in = (org_id IS NULL));
+ALTER TABLE roles DROP CONSTRAINT roles_name_key;
+ALTER TABLE roles ADD CONSTRAINT roles_org_id_name_key UNIQUE NULLS NOT DISTINCT (org_id, name);
+
+INSERT INTO permissions (name, description) VALUES
+    ('organizations.manage', 'Create organizations (host organization only)');
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT id, 'organizations.manage'
+FROM roles
+WHERE name = 'superadmin' AND built_in;
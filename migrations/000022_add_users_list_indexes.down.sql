@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS users_org_id_email_idx;
+DROP INDEX IF EXISTS users_org_id_name_idx;
+DROP INDEX IF EXISTS users_org_id_username_idx;
+DROP INDEX IF EXISTS users_org_id_created_at_idx;
+CREATE INDEX users_org_id_idx ON users (org_id);
+
+DROP INDEX IF EXISTS users_email_trgm_idx;
+DROP INDEX IF EXISTS users_name_trgm_idx;
+DROP INDEX IF EXISTS users_username_trgm_idx;
@@ -0,0 +1,13 @@
+-- Trigram indexes serve the case-insensitive substring search of the user list
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX users_username_trgm_idx ON users USING GIN (username gin_trgm_ops);
+CREATE INDEX users_name_trgm_idx ON users USING GIN (name gin_trgm_ops);
+CREATE INDEX users_email_trgm_idx ON users USING GIN (email gin_trgm_ops);
+
+-- One index per sort key of the user list; the id breaks ties between pages
+DROP INDEX users_org_id_idx;
+CREATE INDEX users_org_id_created_at_idx ON users (org_id, created_at, id);
+CREATE INDEX users_org_id_username_idx ON users (org_id, username, id);
+CREATE INDEX users_org_id_name_idx ON users (org_id, name, id);
+CREATE INDEX users_org_id_email_idx ON users (org_id, email, id);
@@ -0,0 +1,14 @@
+DELETE FROM permissions WHERE name = 'users.purge';
+
+-- Enum values cannot be dropped, so the type is rebuilt. Users that were
+-- deactivated or archived are removed rather than let back in.
+DELETE FROM users WHERE status IN ('deactivated', 'archived');
+
+ALTER TABLE users DROP COLUMN IF EXISTS archived_at;
+
+ALTER TYPE user_status RENAME TO user_status_old;
+CREATE TYPE user_status AS ENUM ('invited', 'active');
+ALTER TABLE users ALTER COLUMN status DROP DEFAULT;
+ALTER TABLE users ALTER COLUMN status TYPE user_status USING status::text::user_status;
+ALTER TABLE users ALTER COLUMN status SET DEFAULT 'active';
+DROP TYPE user_status_old;
@@ -0,0 +1,15 @@
+-- Deactivated users cannot sign in until restored. Archived users are hidden
+-- as if deleted but keep their history, and can be purged for good once the
+-- grace period after archived_at has passed.
+ALTER TYPE user_status ADD VALUE 'deactivated';
+ALTER TYPE user_status ADD VALUE 'archived';
+
+ALTER TABLE users ADD COLUMN archived_at TIMESTAMPTZ;
+
+INSERT INTO permissions (name, description) VALUES
+    ('users.purge', 'Permanently delete archived users after the grace period');
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT id, 'users.purge'
+FROM roles
+WHERE name = 'superadmin' AND built_in;